@@ -0,0 +1,105 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/acl"
+	"github.com/spf13/cobra"
+)
+
+// aclCmd represents the acl command
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Gets and sets the ACLs of objects.",
+}
+
+// aclGetCmd represents the acl get command
+var aclGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Displays the owner and the grantees of an object, or of the objects under a prefix.",
+	Long: `Displays the owner and the grantees of an object, or of the objects under a prefix.
+
+Synopsis
+acl get
+<S3Uri>
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+
+Options
+path (string)
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+A warning is displayed if the bucket has ObjectOwnership=BucketOwnerEnforced, because ACLs are disabled on such buckets.`,
+	Run: acl.RunGet,
+}
+
+// aclSetCmd represents the acl set command
+var aclSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the ACL of an object, or of the objects under a prefix.",
+	Long: `Sets the ACL of an object, or of the objects under a prefix.
+
+Synopsis
+acl set
+<S3Uri>
+[--acl <value> | --grants <value> [--grants <value> ...]]
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+[--dryrun]
+
+Options
+path (string)
+
+--acl (string) The canned ACL to set. Valid values are private, public-read, public-read-write,
+authenticated-read, aws-exec-read, bucket-owner-read and bucket-owner-full-control.
+
+--grants (string) The grant in the form of Permission=Grantee_Type=Grantee_ID.
+Permission is one of read, readacl, writeacl and full.
+Grantee_Type is one of uri, emailaddress and id.
+e.g. --grants read=uri=http://acs.amazonaws.com/groups/global/AllUsers --grants full=id=<canonical-user-id>
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+--dryrun (boolean) Displays the operations that would be performed using the specified command without actually running them.
+
+A warning is displayed if the bucket has ObjectOwnership=BucketOwnerEnforced, because ACLs are disabled on such buckets.
+The objects are processed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: acl.RunSet,
+}
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+	aclCmd.AddCommand(aclGetCmd)
+	aclCmd.AddCommand(aclSetCmd)
+	acl.InitGet(aclGetCmd)
+	acl.InitSet(aclSetCmd)
+}
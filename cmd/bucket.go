@@ -0,0 +1,116 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shogo82148/s3cli-mini/cmd/internal/bucket"
+	"github.com/spf13/cobra"
+)
+
+// bucketCmd represents the bucket command
+var bucketCmd = &cobra.Command{
+	Use:   "bucket",
+	Short: "Manages the configurations of a bucket.",
+	Long: `Manages the configurations of a bucket: policy, cors, lifecycle and website.
+
+The documents are in the same format as the AWS CLI s3api commands,
+e.g. aws s3api put-bucket-cors --cors-configuration, and can be written in JSON or YAML.`,
+}
+
+func init() {
+	rootCmd.AddCommand(bucketCmd)
+	for _, name := range bucket.Resources() {
+		resourceCmd := &cobra.Command{
+			Use:   name,
+			Short: fmt.Sprintf("Gets, puts and deletes the %s of a bucket.", name),
+		}
+		bucketCmd.AddCommand(resourceCmd)
+
+		getCmd := &cobra.Command{
+			Use:   "get",
+			Short: fmt.Sprintf("Displays the %s of a bucket.", name),
+			Long: fmt.Sprintf(`Displays the %s of a bucket.
+
+Synopsis
+bucket %s get
+<S3Uri>
+[--format <value>]
+
+Options
+path (string) s3://bucket
+
+--format (string) The format of the document. Valid values are json and yaml. The default is json.`, name, name),
+			Run: bucket.RunGet,
+		}
+		resourceCmd.AddCommand(getCmd)
+		bucket.InitGet(getCmd)
+
+		putCmd := &cobra.Command{
+			Use:   "put",
+			Short: fmt.Sprintf("Puts the %s of a bucket.", name),
+			Long: fmt.Sprintf(`Puts the %s of a bucket.
+The document is validated locally before sending.
+
+Synopsis
+bucket %s put
+<S3Uri>
+--file <value>
+[--dryrun]
+[--format <value>]
+
+Options
+path (string) s3://bucket
+
+--file (string) The file of the document in JSON or YAML. - means STDIN.
+
+--dryrun (boolean) Displays the difference between the current and the proposed documents without actually putting it.
+
+--format (string) The format of the diff in the dry run mode. Valid values are json and yaml. The default is json.`, name, name),
+			Run: bucket.RunPut,
+		}
+		resourceCmd.AddCommand(putCmd)
+		bucket.InitPut(putCmd)
+
+		deleteCmd := &cobra.Command{
+			Use:   "delete",
+			Short: fmt.Sprintf("Deletes the %s of a bucket.", name),
+			Long: fmt.Sprintf(`Deletes the %s of a bucket.
+
+Synopsis
+bucket %s delete
+<S3Uri>
+[--dryrun]
+[--format <value>]
+
+Options
+path (string) s3://bucket
+
+--dryrun (boolean) Displays the document that would be deleted without actually deleting it.
+
+--format (string) The format of the diff in the dry run mode. Valid values are json and yaml. The default is json.`, name, name),
+			Run: bucket.RunDelete,
+		}
+		resourceCmd.AddCommand(deleteCmd)
+		bucket.InitDelete(deleteCmd)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/cat"
+	"github.com/spf13/cobra"
+)
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat",
+	Short: "Writes the content of an S3 object to STDOUT.",
+	Long: `Writes the content of an S3 object to STDOUT.
+
+Synopsis
+cat
+<S3Uri>
+[--range <value>]
+[--version-id <value>]
+[--raw]
+
+Options
+path (string)
+
+--range (string) Downloads the specified range bytes of the object, e.g. bytes=0-1023, 1024- or -512.
+
+--version-id (string) The version of the object.
+
+--raw (boolean) Do not decode gzip compressed objects.
+
+The gzip compressed objects are decoded automatically, unless --range is specified.`,
+	Run: cat.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(catCmd)
+	cat.Init(catCmd)
+}
@@ -0,0 +1,62 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compares a local directory and an S3 prefix, or two S3 prefixes without transferring.",
+	Long: `Compares a local directory and an S3 prefix, or two S3 prefixes without transferring.
+
+Synopsis
+diff
+<LocalPath> <S3Uri> or <S3Uri> <LocalPath> or <S3Uri> <S3Uri>
+[--json]
+
+Options
+paths (string)
+
+--json (boolean) Displays the differences in JSON format.
+
+The files and the objects only in the first path are reported as "added",
+the ones only in the second path are reported as "removed",
+and the ones in both paths are reported as "changed" with the reason:
+
+size      the sizes are different.
+etag      the ETags are different. For local files, the ETag is computed from the MD5 digest,
+          assuming the multipart uploads use the multipart_chunksize of the s3 settings.
+          The ETags of the objects encrypted with SSE-KMS or SSE-C are not compared.
+checksum  the checksums of the whole objects are different.
+mtime     the local file is newer than the object, if neither ETag nor checksum can be compared.
+
+The exit status is 0 if there are no differences, 1 if there are differences, and 2 if trouble.`,
+	Run: diff.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diff.Init(diffCmd)
+}
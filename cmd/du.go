@@ -0,0 +1,58 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/du"
+	"github.com/spf13/cobra"
+)
+
+// duCmd represents the du command
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Displays the total size and the number of objects per prefix.",
+	Long: `Displays the total size and the number of objects per prefix.
+
+Synopsis
+du
+<S3Uri>
+[--max-depth <value>]
+[--human-readable]
+[--json]
+
+Options
+path (string)
+
+--max-depth (integer) Displays the usage of the prefixes only if they are N or fewer levels below the specified prefix. The default is -1, no limit.
+
+--human-readable (boolean) Displays sizes in human readable format.
+
+--json (boolean) Displays the usage in JSON format.
+
+The usage is broken down by the storage classes.
+The prefixes are listed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: du.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(duCmd)
+	du.Init(duCmd)
+}
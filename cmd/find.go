@@ -0,0 +1,73 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/find"
+	"github.com/spf13/cobra"
+)
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Searches S3 objects under a prefix.",
+	Long: `Searches S3 objects under a prefix.
+
+Synopsis
+find
+<S3Uri>
+[--name <value>]
+[--size <value>]
+[--mtime <value>]
+[--newer-than <value>]
+[--storage-class <value>]
+[--tag <value>]
+[--exec-delete]
+[--print0]
+
+Options
+path (string)
+
+--name (string) Base of the key (the path with the leading prefixes removed) matches the shell pattern, e.g. '*.log'.
+
+--size (string) The size of the object is greater than (+N), less than (-N) or exactly (N) N bytes, e.g. +1G.
+
+--mtime (string) The object was last modified less than (-N) or more than (+N) N ago, e.g. -7d. The units are s, m, h, d and w.
+
+--newer-than (string) The object was last modified after the time, e.g. 2006-01-02 or 2006-01-02T15:04:05Z.
+
+--storage-class (string) The storage class of the object is the value. It can be specified multiple times.
+
+--tag (string) The object has the tag KEY=VALUE, or the tag KEY with any value. It can be specified multiple times.
+
+--exec-delete (boolean) Deletes the found objects.
+
+--print0 (boolean) Separates the found objects by the null character instead of the newline.
+
+All conditions must be satisfied. The listing is streamed, so it runs in constant memory.
+--tag sends a request per object that satisfies the other conditions.`,
+	Run: find.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+	find.Init(findCmd)
+}
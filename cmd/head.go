@@ -0,0 +1,51 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/head"
+	"github.com/spf13/cobra"
+)
+
+// headCmd represents the head command
+var headCmd = &cobra.Command{
+	Use:   "head",
+	Short: "Writes the first lines of an S3 object to STDOUT.",
+	Long: `Writes the first lines of an S3 object to STDOUT.
+
+Synopsis
+head
+<S3Uri>
+[-n <value>]
+
+Options
+path (string)
+
+-n, --lines (integer) The number of lines to print. The default is 10.
+
+The object is read by small ranged GETs, so the whole object is not downloaded.`,
+	Run: head.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(headCmd)
+	head.Init(headCmd)
+}
@@ -0,0 +1,312 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// objectAPI is the subset of the S3 API that acl command uses.
+type objectAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+	GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error)
+	PutObjectAcl(ctx context.Context, params *s3.PutObjectAclInput, optFns ...func(*s3.Options)) (*s3.PutObjectAclOutput, error)
+}
+
+// InitGet initializes flags of acl get command.
+func InitGet(cmd *cobra.Command) {
+	initCommon(cmd.Flags())
+}
+
+// InitSet initializes flags of acl set command.
+func InitSet(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	initCommon(flags)
+	flags.String("acl", "", "The canned ACL to set. e.g. private, public-read, bucket-owner-full-control")
+	flags.StringArray("grants", []string{}, "The grants to set, in the form of Permission=Grantee_Type=Grantee_ID. e.g. read=uri=http://acs.amazonaws.com/groups/global/AllUsers")
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+}
+
+func initCommon(flags *pflag.FlagSet) {
+	flags.Bool("recursive", false, "Command is performed on all objects under the specified prefix.")
+	filter.AddFlags(flags)
+}
+
+// options is the options of acl commands.
+type options struct {
+	batch batch.Options
+
+	// canned and grants are exclusive.
+	canned types.ObjectCannedACL
+	grants Grants
+
+	dryrun bool
+}
+
+// RunGet runs acl get command.
+func RunGet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.get(ctx)
+	})
+}
+
+// RunSet runs acl set command.
+func RunSet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.set(ctx)
+	})
+}
+
+func run(cmd *cobra.Command, args []string, fn func(ctx context.Context, c *client) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || (key == "" && !opts.batch.Recursive) {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	c := &client{
+		svc:    svc,
+		w:      cmd.OutOrStdout(),
+		bucket: bucket,
+		key:    key,
+		opts:   opts,
+	}
+	c.checkOwnership(ctx, cmd.ErrOrStderr())
+	if err := fn(ctx, c); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	recursive, err := flags.GetBool("recursive")
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter.FromFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	opts := &options{
+		batch: batch.Options{
+			Recursive:   recursive,
+			Filter:      f,
+			Concurrency: concurrency,
+		},
+	}
+
+	if flags.Lookup("acl") != nil {
+		name, err := flags.GetString("acl")
+		if err != nil {
+			return nil, err
+		}
+		opts.canned, err = ParseCanned(name)
+		if err != nil {
+			return nil, err
+		}
+		grants, err := flags.GetStringArray("grants")
+		if err != nil {
+			return nil, err
+		}
+		opts.grants, err = ParseGrants(grants)
+		if err != nil {
+			return nil, err
+		}
+		if opts.canned == "" && opts.grants.IsZero() {
+			return nil, errors.New("either --acl or --grants is required")
+		}
+		if opts.canned != "" && !opts.grants.IsZero() {
+			return nil, errors.New("--acl and --grants cannot be specified at the same time")
+		}
+
+		dryrun, err := flags.GetBool("dryrun")
+		if err != nil {
+			return nil, err
+		}
+		opts.dryrun = dryrun
+	}
+	return opts, nil
+}
+
+// describe describes the ACL to set.
+func (o *options) describe() string {
+	if o.canned != "" {
+		return string(o.canned)
+	}
+	var parts []string
+	for _, g := range []struct{ permission, grantees string }{
+		{"read", o.grants.Read},
+		{"readacl", o.grants.ReadACP},
+		{"writeacl", o.grants.WriteACP},
+		{"full", o.grants.FullControl},
+	} {
+		if g.grantees != "" {
+			parts = append(parts, g.permission+"="+g.grantees)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+type client struct {
+	svc    objectAPI
+	bucket string
+	key    string
+	opts   *options
+
+	// mu protects w from the concurrent writes.
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// checkOwnership warns that the ACLs have no effect if the bucket owner enforced setting is enabled.
+// The error of GetBucketOwnershipControls is ignored,
+// because the bucket may have no ownership controls, or the user may not have the permission.
+func (c *client) checkOwnership(ctx context.Context, stderr io.Writer) {
+	out, err := c.svc.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(c.bucket),
+	})
+	if err != nil || out.OwnershipControls == nil {
+		return
+	}
+	for _, rule := range out.OwnershipControls.Rules {
+		if rule.ObjectOwnership == types.ObjectOwnershipBucketOwnerEnforced {
+			fmt.Fprintf(stderr, "Warning: the bucket %s has ObjectOwnership=BucketOwnerEnforced. ACLs are disabled and no longer affect permissions.\n", c.bucket)
+			return
+		}
+	}
+}
+
+// get prints the owner and the grants of the objects.
+func (c *client) get(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		out, err := c.svc.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+			Bucket: aws.String(c.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get the ACL of s3://%s/%s: %w", c.bucket, key, err)
+		}
+
+		// format the ACL before locking, not to block the other workers.
+		var buf bytes.Buffer
+		if c.opts.batch.Recursive {
+			fmt.Fprintf(&buf, "s3://%s/%s\n", c.bucket, key)
+		}
+		printACL(&buf, out.Owner, out.Grants)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, err = c.w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// set sets the canned ACL or the grants to the objects.
+func (c *client) set(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		if c.opts.dryrun {
+			return c.printf("(dryrun) set acl s3://%s/%s: %s\n", c.bucket, key, c.opts.describe())
+		}
+		_, err := c.svc.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+			Bucket:           aws.String(c.bucket),
+			Key:              aws.String(key),
+			ACL:              c.opts.canned,
+			GrantRead:        s3path.NullableString(c.opts.grants.Read),
+			GrantReadACP:     s3path.NullableString(c.opts.grants.ReadACP),
+			GrantWriteACP:    s3path.NullableString(c.opts.grants.WriteACP),
+			GrantFullControl: s3path.NullableString(c.opts.grants.FullControl),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set the ACL of s3://%s/%s: %w", c.bucket, key, err)
+		}
+		return c.printf("set acl s3://%s/%s: %s\n", c.bucket, key, c.opts.describe())
+	})
+}
+
+func (c *client) printf(format string, a ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.w, format, a...)
+	return err
+}
+
+// printACL writes the owner and the grants in a human readable format.
+func printACL(w io.Writer, owner *types.Owner, grants []types.Grant) {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	if owner != nil {
+		fmt.Fprintf(tw, "Owner:\t%s\n", formatUser(owner.DisplayName, owner.ID))
+	}
+	fmt.Fprintln(tw, "Grants:")
+	for _, g := range grants {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", g.Permission, granteeType(g.Grantee), formatGrantee(g.Grantee))
+	}
+	tw.Flush()
+}
+
+func granteeType(g *types.Grantee) string {
+	if g == nil {
+		return "-"
+	}
+	return string(g.Type)
+}
+
+func formatGrantee(g *types.Grantee) string {
+	if g == nil {
+		return "-"
+	}
+	switch g.Type {
+	case types.TypeGroup:
+		return aws.ToString(g.URI)
+	case types.TypeAmazonCustomerByEmail:
+		return aws.ToString(g.EmailAddress)
+	}
+	return formatUser(g.DisplayName, g.ID)
+}
+
+func formatUser(name, id *string) string {
+	if aws.ToString(name) == "" {
+		return aws.ToString(id)
+	}
+	return fmt.Sprintf("%s (%s)", aws.ToString(name), aws.ToString(id))
+}
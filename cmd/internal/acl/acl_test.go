@@ -0,0 +1,201 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+)
+
+// newFakeACLAPI returns a bucket whose objects are owned by alice, and a.txt is public.
+func newFakeACLAPI() *testutils.FakeS3 {
+	keys := []string{"a.txt", "logs/b.log", "logs/c.txt"}
+	f := testutils.NewFakeS3(keys...)
+	owner := &types.Owner{DisplayName: aws.String("alice"), ID: aws.String("1234")}
+	for _, key := range keys {
+		grants := []types.Grant{
+			{
+				Grantee:    &types.Grantee{Type: types.TypeCanonicalUser, DisplayName: owner.DisplayName, ID: owner.ID},
+				Permission: types.PermissionFullControl,
+			},
+		}
+		if key == "a.txt" {
+			grants = append(grants, types.Grant{
+				Grantee:    &types.Grantee{Type: types.TypeGroup, URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers")},
+				Permission: types.PermissionRead,
+			})
+		}
+		f.ACLs[key] = &s3.GetObjectAclOutput{Owner: owner, Grants: grants}
+	}
+	return f
+}
+
+func newTestClient(svc objectAPI, key string, opts *options) (*client, *bytes.Buffer) {
+	var buf bytes.Buffer
+	opts.batch.Concurrency = 2
+	return &client{svc: svc, w: &buf, bucket: "bucket", key: key, opts: opts}, &buf
+}
+
+func TestGet(t *testing.T) {
+	c, buf := newTestClient(newFakeACLAPI(), "a.txt", &options{})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "Owner: alice (1234)\n" +
+		"Grants:\n" +
+		"  FULL_CONTROL CanonicalUser alice (1234)\n" +
+		"  READ         Group         http://acs.amazonaws.com/groups/global/AllUsers\n"
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestGet_Recursive(t *testing.T) {
+	c, buf := newTestClient(newFakeACLAPI(), "logs/", &options{batch: batch.Options{Recursive: true}})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"s3://bucket/logs/b.log\n", "s3://bucket/logs/c.txt\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in the output, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "a.txt") {
+		t.Errorf("a.txt is not under the prefix, got %q", got)
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Run("canned", func(t *testing.T) {
+		svc := newFakeACLAPI()
+		c, _ := newTestClient(svc, "logs", &options{batch: batch.Options{Recursive: true}, canned: types.ObjectCannedACLPrivate})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for key, in := range svc.ACLInputs {
+			keys = append(keys, key)
+			if in.ACL != types.ObjectCannedACLPrivate {
+				t.Errorf("%s: want private, got %q", key, in.ACL)
+			}
+		}
+		sort.Strings(keys)
+		if got, want := strings.Join(keys, ","), "logs/b.log,logs/c.txt"; got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	})
+
+	t.Run("grants", func(t *testing.T) {
+		grants, err := ParseGrants([]string{"read=uri=http://acs.amazonaws.com/groups/global/AllUsers", "full=id=1234"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		svc := newFakeACLAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{grants: grants})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		in := svc.ACLInputs["a.txt"]
+		if got, want := aws.ToString(in.GrantRead), `uri="http://acs.amazonaws.com/groups/global/AllUsers"`; got != want {
+			t.Errorf("GrantRead: want %s, got %s", want, got)
+		}
+		if got, want := aws.ToString(in.GrantFullControl), `id="1234"`; got != want {
+			t.Errorf("GrantFullControl: want %s, got %s", want, got)
+		}
+		if in.GrantReadACP != nil || in.GrantWriteACP != nil {
+			t.Errorf("unexpected grants: %v, %v", in.GrantReadACP, in.GrantWriteACP)
+		}
+	})
+
+	t.Run("dryrun", func(t *testing.T) {
+		svc := newFakeACLAPI()
+		c, buf := newTestClient(svc, "a.txt", &options{canned: types.ObjectCannedACLPublicRead, dryrun: true})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := buf.String(), "(dryrun) set acl s3://bucket/a.txt: public-read\n"; got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		if len(svc.ACLInputs) != 0 {
+			t.Errorf("the ACL must not be changed in the dry run mode")
+		}
+	})
+}
+
+func TestCheckOwnership(t *testing.T) {
+	tests := []struct {
+		ownership types.ObjectOwnership
+		warn      bool
+	}{
+		{"", false},
+		{types.ObjectOwnershipObjectWriter, false},
+		{types.ObjectOwnershipBucketOwnerPreferred, false},
+		{types.ObjectOwnershipBucketOwnerEnforced, true},
+	}
+	for _, tt := range tests {
+		svc := newFakeACLAPI()
+		svc.Ownership = tt.ownership
+		var stderr bytes.Buffer
+		c, _ := newTestClient(svc, "a.txt", &options{})
+		c.checkOwnership(context.Background(), &stderr)
+		if got := strings.Contains(stderr.String(), "BucketOwnerEnforced"); got != tt.warn {
+			t.Errorf("%q: want warning %t, got %q", tt.ownership, tt.warn, stderr.String())
+		}
+	}
+}
+
+func TestParseGrants(t *testing.T) {
+	got, err := ParseGrants([]string{
+		"read=id=1234",
+		"read=emailaddress=alice@example.com",
+		"readacl=uri=http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+		"writeacl=id=5678",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Grants{
+		Read:     `id="1234", emailaddress="alice@example.com"`,
+		ReadACP:  `uri="http://acs.amazonaws.com/groups/global/AuthenticatedUsers"`,
+		WriteACP: `id="5678"`,
+	}
+	if got != want {
+		t.Errorf("want %#v, got %#v", want, got)
+	}
+
+	for _, grant := range []string{"read", "read=id", "read=id=", "write=id=1234", "read=user=1234"} {
+		if _, err := ParseGrants([]string{grant}); err == nil {
+			t.Errorf("%q: want error, got nil", grant)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := parseOptions(testutils.NewCommand(t, InitSet, "--acl", "bucket-owner-full-control", "--recursive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.canned != types.ObjectCannedACLBucketOwnerFullControl || !opts.batch.Recursive {
+		t.Errorf("unexpected options: %#v", opts)
+	}
+
+	invalid := [][]string{
+		{},
+		{"--acl", "unknown"},
+		{"--acl", "private", "--grants", "read=id=1234"},
+		{"--grants", "read"},
+	}
+	for _, args := range invalid {
+		if _, err := parseOptions(testutils.NewCommand(t, InitSet, args...)); err == nil {
+			t.Errorf("%v: want error, got nil", args)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package acl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ParseCanned parses the name of a canned ACL.
+// It returns an empty ACL for an empty name.
+func ParseCanned(acl string) (types.ObjectCannedACL, error) {
+	switch acl {
+	case "":
+		return "", nil
+	case "private":
+		return types.ObjectCannedACLPrivate, nil
+	case "public-read":
+		return types.ObjectCannedACLPublicRead, nil
+	case "public-read-write":
+		return types.ObjectCannedACLPublicReadWrite, nil
+	case "authenticated-read":
+		return types.ObjectCannedACLAuthenticatedRead, nil
+	case "aws-exec-read":
+		return types.ObjectCannedACLAwsExecRead, nil
+	case "bucket-owner-read":
+		return types.ObjectCannedACLBucketOwnerRead, nil
+	case "bucket-owner-full-control":
+		return types.ObjectCannedACLBucketOwnerFullControl, nil
+	}
+	return "", fmt.Errorf("unknown acl: %s", acl)
+}
+
+// Grants is the explicit grants, in the form of the x-amz-grant-* headers.
+// The empty fields mean no grant of the permission.
+type Grants struct {
+	Read        string
+	ReadACP     string
+	WriteACP    string
+	FullControl string
+}
+
+// IsZero reports whether g has no grant.
+func (g Grants) IsZero() bool {
+	return g == Grants{}
+}
+
+// ParseGrants parses the grants in the same format as --grants of the AWS CLI,
+// e.g. "read=uri=http://acs.amazonaws.com/groups/global/AllUsers" or "full=id=<canonical-user-id>".
+// The permission is one of read, readacl, writeacl and full,
+// and the grantee type is one of uri, emailaddress and id.
+func ParseGrants(grants []string) (Grants, error) {
+	var ret Grants
+	for _, grant := range grants {
+		permission, grantee, ok := strings.Cut(grant, "=")
+		if !ok {
+			return Grants{}, fmt.Errorf("invalid grant: %q, the format is Permission=Grantee_Type=Grantee_ID", grant)
+		}
+		typ, id, ok := strings.Cut(grantee, "=")
+		if !ok || id == "" {
+			return Grants{}, fmt.Errorf("invalid grant: %q, the format is Permission=Grantee_Type=Grantee_ID", grant)
+		}
+		switch typ {
+		case "uri", "emailaddress", "id":
+		default:
+			return Grants{}, fmt.Errorf("invalid grantee type: %q, valid values are 'uri', 'emailaddress' or 'id'", typ)
+		}
+		header := typ + "=" + strconv.Quote(id)
+
+		var dst *string
+		switch permission {
+		case "read":
+			dst = &ret.Read
+		case "readacl":
+			dst = &ret.ReadACP
+		case "writeacl":
+			dst = &ret.WriteACP
+		case "full":
+			dst = &ret.FullControl
+		default:
+			return Grants{}, fmt.Errorf("invalid permission: %q, valid values are 'read', 'readacl', 'writeacl' or 'full'", permission)
+		}
+		if *dst != "" {
+			*dst += ", "
+		}
+		*dst += header
+	}
+	return ret, nil
+}
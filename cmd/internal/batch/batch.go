@@ -0,0 +1,83 @@
+// Package batch runs an operation on an object, or on the objects under a prefix in parallel.
+package batch
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
+)
+
+// Options is the options of Run.
+type Options struct {
+	// Recursive runs the operation on the objects under the prefix.
+	Recursive bool
+
+	// Filter filters the objects by the keys relative to the prefix.
+	// It is used only if Recursive is true.
+	Filter *filter.Filter
+
+	// Concurrency is the maximum number of the operations running concurrently.
+	Concurrency int
+}
+
+// Run calls fn with the key, or with each key of the objects under the prefix if opts.Recursive is true.
+// The calls run in parallel up to opts.Concurrency, and the first error cancels the rest.
+func Run(ctx context.Context, svc interfaces.ObjectListerV2, bucket, key string, opts Options, fn func(ctx context.Context, key string) error) error {
+	if !opts.Recursive {
+		return fn(ctx, key)
+	}
+
+	prefix := key
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(opts.Concurrency, 1))
+	walk := func() error {
+		p := s3.NewListObjectsV2Paginator(svc, &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, obj := range page.Contents {
+				key := aws.ToString(obj.Key)
+				if opts.Filter != nil && !opts.Filter.Match(strings.TrimPrefix(key, prefix)) {
+					continue
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return nil
+				}
+				wg.Go(func() {
+					defer func() { <-sem }()
+					if err := fn(ctx, key); err != nil {
+						cancel(err)
+					}
+				})
+			}
+		}
+		return nil
+	}
+	err := walk()
+	wg.Wait()
+
+	// the cause is the error of fn, or the error of the parent context.
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/spf13/pflag"
+)
+
+// fakeLister lists the keys, two keys per page.
+type fakeLister struct {
+	keys []string
+}
+
+func (f *fakeLister) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	start, _ := strconv.Atoi(aws.ToString(params.ContinuationToken))
+	out := &s3.ListObjectsV2Output{}
+	for i := start; i < len(f.keys); i++ {
+		if len(out.Contents) == 2 {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(strconv.Itoa(i))
+			break
+		}
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(f.keys[i])})
+	}
+	return out, nil
+}
+
+func TestRun(t *testing.T) {
+	svc := &fakeLister{keys: []string{"dir/a.txt", "dir/b.jpg", "dir/c.txt", "dir/sub/d.txt", "dir/e.txt"}}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	filter.AddFlags(flags)
+	if err := flags.Parse([]string{"--exclude", "sub/*", "--exclude", "*.jpg"}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := filter.FromFlags(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var got []string
+	var running, maxRunning atomic.Int32
+	opts := Options{Recursive: true, Filter: f, Concurrency: 2}
+	err = Run(t.Context(), svc, "bucket", "dir", opts, func(ctx context.Context, key string) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{"dir/a.txt", "dir/c.txt", "dir/e.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("want at most 2 concurrent calls, got %d", maxRunning.Load())
+	}
+}
+
+func TestRun_Single(t *testing.T) {
+	var got string
+	err := Run(t.Context(), &fakeLister{}, "bucket", "key", Options{}, func(ctx context.Context, key string) error {
+		got = key
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "key" {
+		t.Errorf("want key, got %q", got)
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = "dir/" + strconv.Itoa(i)
+	}
+	wantErr := errors.New("failed")
+	var calls atomic.Int32
+	err := Run(t.Context(), &fakeLister{keys: keys}, "bucket", "dir/", Options{Recursive: true, Concurrency: 1}, func(ctx context.Context, key string) error {
+		calls.Add(1)
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("want %v, got %v", wantErr, err)
+	}
+	if calls.Load() >= 100 {
+		t.Error("the error doesn't cancel the rest")
+	}
+}
@@ -0,0 +1,402 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+// bucketAPI is the subset of the S3 API that bucket command uses.
+type bucketAPI interface {
+	DeleteBucketCors(ctx context.Context, params *s3.DeleteBucketCorsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketCorsOutput, error)
+	DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error)
+	DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error)
+	DeleteBucketWebsite(ctx context.Context, params *s3.DeleteBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketWebsiteOutput, error)
+	GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error)
+	GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+	GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error)
+	PutBucketCors(ctx context.Context, params *s3.PutBucketCorsInput, optFns ...func(*s3.Options)) (*s3.PutBucketCorsOutput, error)
+	PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error)
+	PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error)
+	PutBucketWebsite(ctx context.Context, params *s3.PutBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error)
+}
+
+// resource is a configuration of a bucket.
+// The documents are the trees decoded from JSON or YAML,
+// the keys of which are same as the AWS CLI, e.g. aws s3api put-bucket-cors --cors-configuration.
+type resource struct {
+	name string
+
+	// get returns the current document, or nil if the bucket has no configuration.
+	get func(ctx context.Context, svc bucketAPI, bucket string) (any, error)
+
+	// check validates the document, and returns the normalized document.
+	check func(doc any) (any, error)
+
+	put    func(ctx context.Context, svc bucketAPI, bucket string, doc any) error
+	delete func(ctx context.Context, svc bucketAPI, bucket string) error
+}
+
+var resources = []*resource{policyResource, corsResource, lifecycleResource, websiteResource}
+
+// Resources returns the names of the configurations that bucket command manages.
+func Resources() []string {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+func lookupResource(name string) (*resource, error) {
+	for _, r := range resources {
+		if r.name == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown bucket configuration: %s", name)
+}
+
+// InitGet initializes flags of bucket <resource> get command.
+func InitGet(cmd *cobra.Command) {
+	cmd.Flags().String("format", "json", "The format of the document. Valid values are json and yaml.")
+}
+
+// InitPut initializes flags of bucket <resource> put command.
+func InitPut(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.String("file", "", "The file of the document in JSON or YAML. - means STDIN.")
+	flags.String("format", "json", "The format of the diff in the dry run mode. Valid values are json and yaml.")
+	flags.Bool("dryrun", false, "Displays the difference between the current and the proposed documents without actually putting it.")
+}
+
+// InitDelete initializes flags of bucket <resource> delete command.
+func InitDelete(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.String("format", "json", "The format of the diff in the dry run mode. Valid values are json and yaml.")
+	flags.Bool("dryrun", false, "Displays the document that would be deleted without actually deleting it.")
+}
+
+// options is the options of bucket commands.
+type options struct {
+	resource *resource
+	bucket   string
+	format   string
+	dryrun   bool
+
+	// document is the validated document to put.
+	document any
+}
+
+// RunGet runs bucket <resource> get command.
+func RunGet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, svc bucketAPI, opts *options) error {
+		return get(ctx, svc, cmd.OutOrStdout(), opts)
+	})
+}
+
+// RunPut runs bucket <resource> put command.
+func RunPut(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, svc bucketAPI, opts *options) error {
+		return put(ctx, svc, cmd.OutOrStdout(), opts)
+	})
+}
+
+// RunDelete runs bucket <resource> delete command.
+func RunDelete(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, svc bucketAPI, opts *options) error {
+		return deleteDocument(ctx, svc, cmd.OutOrStdout(), opts)
+	})
+}
+
+func run(cmd *cobra.Command, args []string, fn func(ctx context.Context, svc bucketAPI, opts *options) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd, args[0])
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, opts.bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := fn(ctx, svc, opts); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command, path string) (*options, error) {
+	// the parent command is the name of the resource, e.g. "bucket cors get".
+	if !cmd.HasParent() {
+		return nil, errors.New("the configuration is not specified")
+	}
+	r, err := lookupResource(cmd.Parent().Name())
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := strings.TrimSuffix(strings.TrimPrefix(path, "s3://"), "/")
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return nil, fmt.Errorf("invalid bucket: %s, the path must be s3://bucket", path)
+	}
+
+	flags := cmd.Flags()
+	format, err := flags.GetString("format")
+	if err != nil {
+		return nil, err
+	}
+	if format != "json" && format != "yaml" {
+		return nil, fmt.Errorf("invalid format: %s, valid values are json and yaml", format)
+	}
+	opts := &options{
+		resource: r,
+		bucket:   bucket,
+		format:   format,
+	}
+
+	if flags.Lookup("file") != nil {
+		file, err := flags.GetString("file")
+		if err != nil {
+			return nil, err
+		}
+		if file == "" {
+			return nil, errors.New("--file is required")
+		}
+		doc, err := readDocument(cmd.InOrStdin(), file)
+		if err != nil {
+			return nil, err
+		}
+		opts.document, err = r.check(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", r.name, err)
+		}
+	}
+	if flags.Lookup("dryrun") != nil {
+		opts.dryrun, err = flags.GetBool("dryrun")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func get(ctx context.Context, svc bucketAPI, w io.Writer, opts *options) error {
+	doc, err := opts.resource.get(ctx, svc, opts.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get the %s of s3://%s: %w", opts.resource.name, opts.bucket, err)
+	}
+	if doc == nil {
+		return fmt.Errorf("the bucket s3://%s has no %s", opts.bucket, opts.resource.name)
+	}
+	data, err := encode(doc, opts.format)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func put(ctx context.Context, svc bucketAPI, w io.Writer, opts *options) error {
+	r := opts.resource
+	if opts.dryrun {
+		return printDiff(ctx, svc, w, opts, opts.document)
+	}
+	if err := r.put(ctx, svc, opts.bucket, opts.document); err != nil {
+		return fmt.Errorf("failed to put the %s of s3://%s: %w", r.name, opts.bucket, err)
+	}
+	_, err := fmt.Fprintf(w, "put %s: s3://%s\n", r.name, opts.bucket)
+	return err
+}
+
+func deleteDocument(ctx context.Context, svc bucketAPI, w io.Writer, opts *options) error {
+	r := opts.resource
+	if opts.dryrun {
+		return printDiff(ctx, svc, w, opts, nil)
+	}
+	if err := r.delete(ctx, svc, opts.bucket); err != nil {
+		return fmt.Errorf("failed to delete the %s of s3://%s: %w", r.name, opts.bucket, err)
+	}
+	_, err := fmt.Fprintf(w, "delete %s: s3://%s\n", r.name, opts.bucket)
+	return err
+}
+
+// printDiff prints the difference between the current document and the proposed document.
+// The proposed document is nil for deletion.
+func printDiff(ctx context.Context, svc bucketAPI, w io.Writer, opts *options, proposed any) error {
+	r := opts.resource
+	current, err := r.get(ctx, svc, opts.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get the %s of s3://%s: %w", r.name, opts.bucket, err)
+	}
+
+	var a, b []byte
+	if current != nil {
+		if a, err = encode(current, opts.format); err != nil {
+			return err
+		}
+	}
+	if proposed != nil {
+		if b, err = encode(proposed, opts.format); err != nil {
+			return err
+		}
+	}
+
+	verb := "put"
+	if proposed == nil {
+		verb = "delete"
+	}
+	name := fmt.Sprintf("s3://%s (%s)", opts.bucket, r.name)
+	diff := unifiedDiff(name+" current", name+" proposed", string(a), string(b))
+	if diff == "" {
+		_, err = fmt.Fprintf(w, "(dryrun) %s %s: s3://%s (no changes)\n", verb, r.name, opts.bucket)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "(dryrun) %s %s: s3://%s\n%s", verb, r.name, opts.bucket, diff)
+	return err
+}
+
+// readDocument reads the document in JSON or YAML from the file.
+func readDocument(stdin io.Reader, file string) (any, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+// decode decodes the document in JSON or YAML.
+// JSON is decoded by the YAML decoder, because YAML is a superset of JSON.
+func decode(data []byte) (any, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the document: %w", err)
+	}
+	if doc == nil {
+		return nil, errors.New("the document is empty")
+	}
+	return doc, nil
+}
+
+// encode encodes the document in the format.
+func encode(doc any, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == "yaml" {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toTree converts v into the tree of the document.
+// The nulls, the empty strings and the empty objects are removed,
+// because the types of the SDK have no omitempty tags.
+func toTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return prune(doc), nil
+}
+
+func prune(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			value = prune(value)
+			if value == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = value
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = prune(value)
+		}
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	case float64:
+		// the integers are formatted without exponents in YAML.
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	}
+	return v
+}
+
+// fromTree converts the tree of the document into T.
+// The unknown fields are reported as errors, to find typos before sending.
+func fromTree[T any](doc any) (*T, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func isAPIError(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
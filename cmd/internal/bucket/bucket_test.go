@@ -0,0 +1,334 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeBucketAPI keeps the configurations of a bucket in memory.
+type fakeBucketAPI struct {
+	policy    *string
+	cors      []types.CORSRule
+	lifecycle []types.LifecycleRule
+	website   *types.WebsiteConfiguration
+}
+
+func notFound(code string) error {
+	return &smithy.GenericAPIError{Code: code}
+}
+
+func (f *fakeBucketAPI) DeleteBucketCors(ctx context.Context, params *s3.DeleteBucketCorsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketCorsOutput, error) {
+	f.cors = nil
+	return &s3.DeleteBucketCorsOutput{}, nil
+}
+
+func (f *fakeBucketAPI) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	f.lifecycle = nil
+	return &s3.DeleteBucketLifecycleOutput{}, nil
+}
+
+func (f *fakeBucketAPI) DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error) {
+	f.policy = nil
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
+func (f *fakeBucketAPI) DeleteBucketWebsite(ctx context.Context, params *s3.DeleteBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketWebsiteOutput, error) {
+	f.website = nil
+	return &s3.DeleteBucketWebsiteOutput{}, nil
+}
+
+func (f *fakeBucketAPI) GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error) {
+	if f.cors == nil {
+		return nil, notFound("NoSuchCORSConfiguration")
+	}
+	return &s3.GetBucketCorsOutput{CORSRules: f.cors}, nil
+}
+
+func (f *fakeBucketAPI) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if f.lifecycle == nil {
+		return nil, notFound("NoSuchLifecycleConfiguration")
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: f.lifecycle}, nil
+}
+
+func (f *fakeBucketAPI) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	if f.policy == nil {
+		return nil, notFound("NoSuchBucketPolicy")
+	}
+	return &s3.GetBucketPolicyOutput{Policy: f.policy}, nil
+}
+
+func (f *fakeBucketAPI) GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error) {
+	if f.website == nil {
+		return nil, notFound("NoSuchWebsiteConfiguration")
+	}
+	return &s3.GetBucketWebsiteOutput{
+		ErrorDocument:         f.website.ErrorDocument,
+		IndexDocument:         f.website.IndexDocument,
+		RedirectAllRequestsTo: f.website.RedirectAllRequestsTo,
+		RoutingRules:          f.website.RoutingRules,
+	}, nil
+}
+
+func (f *fakeBucketAPI) PutBucketCors(ctx context.Context, params *s3.PutBucketCorsInput, optFns ...func(*s3.Options)) (*s3.PutBucketCorsOutput, error) {
+	f.cors = params.CORSConfiguration.CORSRules
+	return &s3.PutBucketCorsOutput{}, nil
+}
+
+func (f *fakeBucketAPI) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	f.lifecycle = params.LifecycleConfiguration.Rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (f *fakeBucketAPI) PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	f.policy = params.Policy
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+
+func (f *fakeBucketAPI) PutBucketWebsite(ctx context.Context, params *s3.PutBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error) {
+	f.website = params.WebsiteConfiguration
+	return &s3.PutBucketWebsiteOutput{}, nil
+}
+
+func TestGet(t *testing.T) {
+	svc := &fakeBucketAPI{
+		cors: []types.CORSRule{
+			{
+				AllowedMethods: []string{"GET"},
+				AllowedOrigins: []string{"*"},
+				MaxAgeSeconds:  aws.Int32(3000),
+			},
+		},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := get(context.Background(), svc, &buf, &options{resource: corsResource, bucket: "bucket", format: "json"}); err != nil {
+			t.Fatal(err)
+		}
+		want := `{
+  "CORSRules": [
+    {
+      "AllowedMethods": [
+        "GET"
+      ],
+      "AllowedOrigins": [
+        "*"
+      ],
+      "MaxAgeSeconds": 3000
+    }
+  ]
+}
+`
+		if got := buf.String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := get(context.Background(), svc, &buf, &options{resource: corsResource, bucket: "bucket", format: "yaml"}); err != nil {
+			t.Fatal(err)
+		}
+		want := `CORSRules:
+  - AllowedMethods:
+      - GET
+    AllowedOrigins:
+      - '*'
+    MaxAgeSeconds: 3000
+`
+		if got := buf.String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := get(context.Background(), svc, &buf, &options{resource: websiteResource, bucket: "bucket", format: "json"})
+		if err == nil || !strings.Contains(err.Error(), "has no website") {
+			t.Errorf("want not found error, got %v", err)
+		}
+	})
+}
+
+func TestPut(t *testing.T) {
+	t.Run("lifecycle in yaml", func(t *testing.T) {
+		svc := &fakeBucketAPI{}
+		doc, err := decode([]byte(`
+Rules:
+  - ID: expire-logs
+    Status: Enabled
+    Filter:
+      Prefix: logs/
+    Expiration:
+      Days: 30
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err = lifecycleResource.check(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := put(context.Background(), svc, &buf, &options{resource: lifecycleResource, bucket: "bucket", format: "json", document: doc}); err != nil {
+			t.Fatal(err)
+		}
+		if len(svc.lifecycle) != 1 {
+			t.Fatalf("want 1 rule, got %d", len(svc.lifecycle))
+		}
+		rule := svc.lifecycle[0]
+		if aws.ToString(rule.Filter.Prefix) != "logs/" || aws.ToInt32(rule.Expiration.Days) != 30 || rule.Status != types.ExpirationStatusEnabled {
+			t.Errorf("unexpected rule: %#v", rule)
+		}
+		if got, want := buf.String(), "put lifecycle: s3://bucket\n"; got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	})
+
+	t.Run("policy in json", func(t *testing.T) {
+		svc := &fakeBucketAPI{}
+		doc, err := decode([]byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err = policyResource.check(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := put(context.Background(), svc, &buf, &options{resource: policyResource, bucket: "bucket", format: "json", document: doc}); err != nil {
+			t.Fatal(err)
+		}
+		want := `{"Statement":[{"Action":"s3:GetObject","Effect":"Allow","Principal":"*","Resource":"arn:aws:s3:::bucket/*"}],"Version":"2012-10-17"}`
+		if got := aws.ToString(svc.policy); got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	})
+
+	t.Run("dryrun", func(t *testing.T) {
+		svc := &fakeBucketAPI{
+			website: &types.WebsiteConfiguration{
+				IndexDocument: &types.IndexDocument{Suffix: aws.String("index.html")},
+			},
+		}
+		doc, err := decode([]byte("IndexDocument:\n  Suffix: index.html\nErrorDocument:\n  Key: 404.html\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err = websiteResource.check(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := put(context.Background(), svc, &buf, &options{resource: websiteResource, bucket: "bucket", format: "yaml", dryrun: true, document: doc}); err != nil {
+			t.Fatal(err)
+		}
+		want := `(dryrun) put website: s3://bucket
+--- s3://bucket (website) current
++++ s3://bucket (website) proposed
+@@ -1,2 +1,4 @@
++ErrorDocument:
++  Key: 404.html
+ IndexDocument:
+   Suffix: index.html
+`
+		if got := buf.String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		if svc.website.ErrorDocument != nil {
+			t.Error("the configuration must not be changed in the dry run mode")
+		}
+	})
+}
+
+func TestDelete_DryRun(t *testing.T) {
+	svc := &fakeBucketAPI{policy: aws.String(`{"Statement":[]}`)}
+	var buf bytes.Buffer
+	if err := deleteDocument(context.Background(), svc, &buf, &options{resource: policyResource, bucket: "bucket", format: "json", dryrun: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `(dryrun) delete policy: s3://bucket
+--- s3://bucket (policy) current
++++ s3://bucket (policy) proposed
+@@ -1,3 +0,0 @@
+-{
+-  "Statement": []
+-}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if svc.policy == nil {
+		t.Error("the policy must not be deleted in the dry run mode")
+	}
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	tests := []struct {
+		resource *resource
+		doc      string
+	}{
+		{policyResource, `[]`},
+		{policyResource, `{"Statement":[]}`},
+		{policyResource, `{"Statement":[{"Effect":"Permit","Principal":"*","Action":"s3:*","Resource":"*"}]}`},
+		{policyResource, `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"}]}`},
+		{policyResource, `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","NotAction":"s3:Get*","Resource":"*"}]}`},
+		{corsResource, `{"CORSRules":[]}`},
+		{corsResource, `{"CORSRules":[{"AllowedMethods":["PATCH"],"AllowedOrigins":["*"]}]}`},
+		{corsResource, `{"CORSRules":[{"AllowedMethods":["GET"]}]}`},
+		{corsResource, `{"CORSRule":[{"AllowedMethods":["GET"],"AllowedOrigins":["*"]}]}`}, // typo
+		{lifecycleResource, `{"Rules":[{"Status":"On","Expiration":{"Days":1}}]}`},
+		{lifecycleResource, `{"Rules":[{"Status":"Enabled"}]}`},
+		{lifecycleResource, `{"Rules":[{"ID":"a","Status":"Enabled","Expiration":{"Days":1}},{"ID":"a","Status":"Enabled","Expiration":{"Days":2}}]}`},
+		{websiteResource, `{"ErrorDocument":{"Key":"error.html"}}`},
+		{websiteResource, `{"IndexDocument":{"Suffix":"dir/index.html"}}`},
+		{websiteResource, `{"IndexDocument":{"Suffix":"index.html"},"RedirectAllRequestsTo":{"HostName":"example.com"}}`},
+	}
+	for _, tt := range tests {
+		doc, err := decode([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tt.resource.check(doc); err == nil {
+			t.Errorf("%s %s: want error, got nil", tt.resource.name, tt.doc)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n"
+	want := `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -11,5 +11,5 @@
+ 11
+ 12
+ 13
+-14
+ 15
++16
+`
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("want no diff, got %q", got)
+	}
+}
@@ -0,0 +1,308 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCORSRules is the maximum number of the CORS rules of a bucket.
+const maxCORSRules = 100
+
+// maxLifecycleRules is the maximum number of the lifecycle rules of a bucket.
+const maxLifecycleRules = 1000
+
+var policyResource = &resource{
+	name: "policy",
+	get: func(ctx context.Context, svc bucketAPI, bucket string) (any, error) {
+		out, err := svc.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: aws.String(bucket),
+		})
+		if isAPIError(err, "NoSuchBucketPolicy") {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var doc any
+		if err := json.Unmarshal([]byte(aws.ToString(out.Policy)), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse the policy: %w", err)
+		}
+		return doc, nil
+	},
+	check: func(doc any) (any, error) {
+		return doc, validatePolicy(doc)
+	},
+	put: func(ctx context.Context, svc bucketAPI, bucket string, doc any) error {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = svc.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: aws.String(bucket),
+			Policy: aws.String(string(data)),
+		})
+		return err
+	},
+	delete: func(ctx context.Context, svc bucketAPI, bucket string) error {
+		_, err := svc.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+			Bucket: aws.String(bucket),
+		})
+		return err
+	},
+}
+
+// validatePolicy checks the structure of the bucket policy.
+// The semantics, e.g. the actions and the principals, are checked by S3.
+func validatePolicy(doc any) error {
+	policy, ok := doc.(map[string]any)
+	if !ok {
+		return errors.New("the policy must be an object")
+	}
+	statements, ok := policy["Statement"].([]any)
+	if !ok || len(statements) == 0 {
+		return errors.New("the policy must have one or more statements in Statement")
+	}
+	for i, s := range statements {
+		statement, ok := s.(map[string]any)
+		if !ok {
+			return fmt.Errorf("Statement[%d]: the statement must be an object", i)
+		}
+		switch statement["Effect"] {
+		case "Allow", "Deny":
+		default:
+			return fmt.Errorf("Statement[%d]: Effect must be Allow or Deny", i)
+		}
+		for _, pair := range [][2]string{{"Principal", "NotPrincipal"}, {"Action", "NotAction"}, {"Resource", "NotResource"}} {
+			_, ok1 := statement[pair[0]]
+			_, ok2 := statement[pair[1]]
+			if ok1 == ok2 {
+				return fmt.Errorf("Statement[%d]: exactly one of %s and %s is required", i, pair[0], pair[1])
+			}
+		}
+	}
+	return nil
+}
+
+var corsResource = &resource{
+	name: "cors",
+	get: func(ctx context.Context, svc bucketAPI, bucket string) (any, error) {
+		out, err := svc.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+			Bucket: aws.String(bucket),
+		})
+		if isAPIError(err, "NoSuchCORSConfiguration") {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return toTree(&types.CORSConfiguration{
+			CORSRules: out.CORSRules,
+		})
+	},
+	check: checkTyped(validateCORS),
+	put: func(ctx context.Context, svc bucketAPI, bucket string, doc any) error {
+		conf, err := fromTree[types.CORSConfiguration](doc)
+		if err != nil {
+			return err
+		}
+		_, err = svc.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+			Bucket:            aws.String(bucket),
+			CORSConfiguration: conf,
+		})
+		return err
+	},
+	delete: func(ctx context.Context, svc bucketAPI, bucket string) error {
+		_, err := svc.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{
+			Bucket: aws.String(bucket),
+		})
+		return err
+	},
+}
+
+var corsMethods = []string{"GET", "PUT", "POST", "DELETE", "HEAD"}
+
+func validateCORS(conf *types.CORSConfiguration) error {
+	if len(conf.CORSRules) == 0 {
+		return errors.New("the configuration must have one or more rules in CORSRules")
+	}
+	if len(conf.CORSRules) > maxCORSRules {
+		return fmt.Errorf("too many rules: %d, a bucket can have up to %d CORS rules", len(conf.CORSRules), maxCORSRules)
+	}
+	for i, rule := range conf.CORSRules {
+		if len(rule.AllowedMethods) == 0 {
+			return fmt.Errorf("CORSRules[%d]: AllowedMethods is required", i)
+		}
+		for _, m := range rule.AllowedMethods {
+			if !slices.Contains(corsMethods, m) {
+				return fmt.Errorf("CORSRules[%d]: invalid method %q, valid values are %s", i, m, strings.Join(corsMethods, ", "))
+			}
+		}
+		if len(rule.AllowedOrigins) == 0 {
+			return fmt.Errorf("CORSRules[%d]: AllowedOrigins is required", i)
+		}
+		if rule.MaxAgeSeconds != nil && *rule.MaxAgeSeconds < 0 {
+			return fmt.Errorf("CORSRules[%d]: invalid MaxAgeSeconds: %d", i, *rule.MaxAgeSeconds)
+		}
+	}
+	return nil
+}
+
+var lifecycleResource = &resource{
+	name: "lifecycle",
+	get: func(ctx context.Context, svc bucketAPI, bucket string) (any, error) {
+		out, err := svc.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: aws.String(bucket),
+		})
+		if isAPIError(err, "NoSuchLifecycleConfiguration") {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return toTree(&types.BucketLifecycleConfiguration{
+			Rules: out.Rules,
+		})
+	},
+	check: checkTyped(validateLifecycle),
+	put: func(ctx context.Context, svc bucketAPI, bucket string, doc any) error {
+		conf, err := fromTree[types.BucketLifecycleConfiguration](doc)
+		if err != nil {
+			return err
+		}
+		_, err = svc.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 aws.String(bucket),
+			LifecycleConfiguration: conf,
+		})
+		return err
+	},
+	delete: func(ctx context.Context, svc bucketAPI, bucket string) error {
+		_, err := svc.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(bucket),
+		})
+		return err
+	},
+}
+
+func validateLifecycle(conf *types.BucketLifecycleConfiguration) error {
+	if len(conf.Rules) == 0 {
+		return errors.New("the configuration must have one or more rules in Rules")
+	}
+	if len(conf.Rules) > maxLifecycleRules {
+		return fmt.Errorf("too many rules: %d, a bucket can have up to %d lifecycle rules", len(conf.Rules), maxLifecycleRules)
+	}
+	ids := make(map[string]struct{}, len(conf.Rules))
+	for i, rule := range conf.Rules {
+		switch rule.Status {
+		case types.ExpirationStatusEnabled, types.ExpirationStatusDisabled:
+		default:
+			return fmt.Errorf("Rules[%d]: Status must be Enabled or Disabled", i)
+		}
+		if id := aws.ToString(rule.ID); id != "" {
+			if len(id) > 255 {
+				return fmt.Errorf("Rules[%d]: ID is too long", i)
+			}
+			if _, ok := ids[id]; ok {
+				return fmt.Errorf("Rules[%d]: duplicated ID %q", i, id)
+			}
+			ids[id] = struct{}{}
+		}
+		if rule.Filter != nil && rule.Prefix != nil {
+			return fmt.Errorf("Rules[%d]: Filter and Prefix cannot be specified at the same time", i)
+		}
+		if rule.Expiration == nil && rule.AbortIncompleteMultipartUpload == nil && rule.NoncurrentVersionExpiration == nil &&
+			len(rule.Transitions) == 0 && len(rule.NoncurrentVersionTransitions) == 0 {
+			return fmt.Errorf("Rules[%d]: one or more actions are required", i)
+		}
+	}
+	return nil
+}
+
+var websiteResource = &resource{
+	name: "website",
+	get: func(ctx context.Context, svc bucketAPI, bucket string) (any, error) {
+		out, err := svc.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{
+			Bucket: aws.String(bucket),
+		})
+		if isAPIError(err, "NoSuchWebsiteConfiguration") {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return toTree(&types.WebsiteConfiguration{
+			ErrorDocument:         out.ErrorDocument,
+			IndexDocument:         out.IndexDocument,
+			RedirectAllRequestsTo: out.RedirectAllRequestsTo,
+			RoutingRules:          out.RoutingRules,
+		})
+	},
+	check: checkTyped(validateWebsite),
+	put: func(ctx context.Context, svc bucketAPI, bucket string, doc any) error {
+		conf, err := fromTree[types.WebsiteConfiguration](doc)
+		if err != nil {
+			return err
+		}
+		_, err = svc.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+			Bucket:               aws.String(bucket),
+			WebsiteConfiguration: conf,
+		})
+		return err
+	},
+	delete: func(ctx context.Context, svc bucketAPI, bucket string) error {
+		_, err := svc.DeleteBucketWebsite(ctx, &s3.DeleteBucketWebsiteInput{
+			Bucket: aws.String(bucket),
+		})
+		return err
+	},
+}
+
+func validateWebsite(conf *types.WebsiteConfiguration) error {
+	if conf.RedirectAllRequestsTo != nil {
+		if conf.IndexDocument != nil || conf.ErrorDocument != nil || len(conf.RoutingRules) > 0 {
+			return errors.New("RedirectAllRequestsTo cannot be specified with the other settings")
+		}
+		if aws.ToString(conf.RedirectAllRequestsTo.HostName) == "" {
+			return errors.New("RedirectAllRequestsTo: HostName is required")
+		}
+		return nil
+	}
+	if conf.IndexDocument == nil {
+		return errors.New("either IndexDocument or RedirectAllRequestsTo is required")
+	}
+	suffix := aws.ToString(conf.IndexDocument.Suffix)
+	if suffix == "" || strings.Contains(suffix, "/") {
+		return fmt.Errorf("IndexDocument: invalid Suffix %q, it must be non-empty and must not contain slashes", suffix)
+	}
+	if conf.ErrorDocument != nil && aws.ToString(conf.ErrorDocument.Key) == "" {
+		return errors.New("ErrorDocument: Key is required")
+	}
+	for i, rule := range conf.RoutingRules {
+		if rule.Redirect == nil {
+			return fmt.Errorf("RoutingRules[%d]: Redirect is required", i)
+		}
+	}
+	return nil
+}
+
+// checkTyped returns the check function of the resource that is typed as T.
+// The document is normalized by converting into T and back.
+func checkTyped[T any](validate func(*T) error) func(doc any) (any, error) {
+	return func(doc any) (any, error) {
+		v, err := fromTree[T](doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := validate(v); err != nil {
+			return nil, err
+		}
+		return toTree(v)
+	}
+}
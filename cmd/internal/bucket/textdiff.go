@@ -0,0 +1,155 @@
+package bucket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of the unchanged lines around the changes in the unified diff.
+const contextLines = 3
+
+// edit is a line of the edit script.
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns the difference between a and b in the unified format.
+// It returns an empty string if a and b are same.
+func unifiedDiff(fromName, toName, a, b string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var buf strings.Builder
+	for i := 0; i < len(edits); {
+		// skip the unchanged lines.
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// find the end of the hunk.
+		// the hunks that are closer than twice of contextLines are merged.
+		start := max(i-contextLines, 0)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*contextLines {
+				break
+			}
+		}
+		end = min(end+contextLines, len(edits))
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&buf, edits, start, end)
+		i = end
+	}
+	return buf.String()
+}
+
+func writeHunk(buf *strings.Builder, edits []edit, start, end int) {
+	// count the lines before the hunk, to get the line numbers.
+	var fromLine, toLine int
+	for _, e := range edits[:start] {
+		if e.op != '+' {
+			fromLine++
+		}
+		if e.op != '-' {
+			toLine++
+		}
+	}
+	var fromCount, toCount int
+	for _, e := range edits[start:end] {
+		if e.op != '+' {
+			fromCount++
+		}
+		if e.op != '-' {
+			toCount++
+		}
+	}
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, e := range edits[start:end] {
+		buf.WriteByte(e.op)
+		buf.WriteString(e.line)
+		buf.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the range of a hunk, in the same manner as GNU diff.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	}
+	if count == 1 {
+		return fmt.Sprint(line + 1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the edit script from a to b, based on the longest common subsequence.
+// The documents are small enough for the O(N*M) algorithm,
+// and the common prefix and suffix are skipped to make it faster for the usual small changes.
+func diffLines(a, b []string) []edit {
+	var prefix, suffix []edit
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, edit{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, edit{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := prefix
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	for k := len(suffix) - 1; k >= 0; k-- {
+		edits = append(edits, suffix[k])
+	}
+	return edits
+}
@@ -0,0 +1,149 @@
+package cat
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.String("range", "", "Downloads the specified range bytes of the object, e.g. bytes=0-1023, 1024- or -512.")
+	flags.String("version-id", "", "The version of the object.")
+	flags.Bool("raw", false, "Do not decode gzip compressed objects.")
+}
+
+// objectGetter is the subset of S3 API that cat command uses.
+type objectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// options is the options of cat command.
+type options struct {
+	rng       string
+	versionID string
+	raw       bool
+}
+
+var rangePattern = regexp.MustCompile(`^bytes=(\d+-\d*|-\d+)$`)
+
+// Run runs cat command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || key == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	if err := cat(ctx, svc, w, bucket, key, opts); err != nil {
+		w.Flush()
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := w.Flush(); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	rng, err := flags.GetString("range")
+	if err != nil {
+		return nil, err
+	}
+	versionID, err := flags.GetString("version-id")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := flags.GetBool("raw")
+	if err != nil {
+		return nil, err
+	}
+
+	if rng != "" {
+		if !strings.HasPrefix(rng, "bytes=") {
+			rng = "bytes=" + rng
+		}
+		if !rangePattern.MatchString(rng) {
+			return nil, fmt.Errorf("invalid range: %s", rng)
+		}
+	}
+	return &options{
+		rng:       rng,
+		versionID: versionID,
+		raw:       raw,
+	}, nil
+}
+
+// cat writes the body of the object to w.
+// The gzip compressed body is decoded, unless the range is specified or opts.raw is true.
+func cat(ctx context.Context, svc objectGetter, w io.Writer, bucket, key string, opts *options) error {
+	resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		Range:     s3path.NullableString(opts.rng),
+		VersionId: s3path.NullableString(opts.versionID),
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if !opts.raw && opts.rng == "" {
+		br := bufio.NewReader(resp.Body)
+		if isGzip(br) {
+			zr, err := gzip.NewReader(br)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			body = zr
+		} else {
+			body = br
+		}
+	}
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// isGzip reports whether the body is compressed by gzip.
+// It checks the magic number rather than Content-Encoding,
+// because the objects uploaded as *.gz files usually don't have Content-Encoding.
+func isGzip(br *bufio.Reader) bool {
+	magic, err := br.Peek(2)
+	return err == nil && magic[0] == 0x1f && magic[1] == 0x8b
+}
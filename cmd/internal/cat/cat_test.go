@@ -0,0 +1,104 @@
+package cat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/spf13/cobra"
+)
+
+type fakeObjectGetter struct {
+	data  []byte
+	input *s3.GetObjectInput
+}
+
+func (f *fakeObjectGetter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.input = params
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(f.data)),
+	}, nil
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCat(t *testing.T) {
+	compressed := gzipData(t, "hello world\n")
+	cases := []struct {
+		name string
+		data []byte
+		opts options
+		want []byte
+	}{
+		{"plain", []byte("hello world\n"), options{}, []byte("hello world\n")},
+		{"short", []byte("h"), options{}, []byte("h")},
+		{"gzip", compressed, options{}, []byte("hello world\n")},
+		{"raw", compressed, options{raw: true}, compressed},
+		{"range", compressed, options{rng: "bytes=0-9"}, compressed},
+	}
+	for _, tc := range cases {
+		svc := &fakeObjectGetter{data: tc.data}
+		var buf bytes.Buffer
+		if err := cat(t.Context(), svc, &buf, "bucket", "key", &tc.opts); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), tc.want) {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, buf.Bytes())
+		}
+		if got := aws.ToString(svc.input.Range); got != tc.opts.rng {
+			t.Errorf("%s: want range %q, got %q", tc.name, tc.opts.rng, got)
+		}
+	}
+}
+
+func TestParseOptions_Range(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"", "", true},
+		{"bytes=0-1023", "bytes=0-1023", true},
+		{"1024-", "bytes=1024-", true},
+		{"-512", "bytes=-512", true},
+		{"foo", "", false},
+		{"bytes=-", "", false},
+	}
+	for _, tc := range cases {
+		cmd := &cobra.Command{}
+		Init(cmd)
+		if err := cmd.ParseFlags([]string{"--range", tc.in}); err != nil {
+			t.Fatal(err)
+		}
+		opts, err := parseOptions(cmd)
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("%q: want error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if opts.rng != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.in, tc.want, opts.rng)
+		}
+	}
+}
@@ -2,12 +2,19 @@ package config
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +26,19 @@ var awsConfig aws.Config
 var awsRegion string
 var awsProfile string
 var endpointURL string
+var addressingStyle string
+var useAccelerateEndpoint bool
+var useDualstackEndpoint bool
+var caBundle string
+var noVerifySSL bool
+var connectTimeout int
+var readTimeout int
+var maxAttempts int
+var retryMode string
+var roleARN string
+var roleSessionName string
+var externalID string
+var mfaSerial string
 
 // InitFlag initializes global configure.
 func InitFlag(cmd *cobra.Command) {
@@ -27,6 +47,24 @@ func InitFlag(cmd *cobra.Command) {
 	flags.StringVar(&awsRegion, "region", "", "The region to use. Overrides config/env settings.")
 	flags.StringVar(&awsProfile, "profile", "", "Use a specific profile from your credential file.")
 	flags.StringVar(&endpointURL, "endpoint-url", "", "Overrides command's default URL with the given URL.")
+	flags.StringVar(&addressingStyle, "addressing-style", "", "The addressing style of S3 requests. path, virtual or auto. (default auto)")
+	flags.BoolVar(&useAccelerateEndpoint, "use-accelerate-endpoint", false, "Use the S3 Transfer Acceleration endpoint.")
+	flags.BoolVar(&useDualstackEndpoint, "use-dualstack-endpoint", false, "Use the S3 dual-stack (IPv4 and IPv6) endpoint.")
+	flags.StringVar(&caBundle, "ca-bundle", "", "The CA certificate bundle to use when verifying SSL certificates.")
+	flags.BoolVar(&noVerifySSL, "no-verify-ssl", false, "By default, SSL certificates are verified. This option overrides the default behavior of verifying SSL certificates.")
+	flags.IntVar(&connectTimeout, "cli-connect-timeout", 60, "The maximum socket connect time in seconds. If the value is set to 0, the socket connect will be blocking and not timeout.")
+	flags.IntVar(&readTimeout, "cli-read-timeout", 60, "The maximum socket read time in seconds. If the value is set to 0, the socket read will be blocking and not timeout.")
+	flags.IntVar(&maxAttempts, "max-attempts", 0, "The maximum number of attempts including the initial request. Overrides AWS_MAX_ATTEMPTS and max_attempts in the profile.")
+	flags.StringVar(&retryMode, "retry-mode", "", "The retry mode. standard or adaptive. Overrides AWS_RETRY_MODE and retry_mode in the profile.")
+	flags.StringVar(&roleARN, "role-arn", "", "The ARN of the role to assume. The credentials of the profile are used for assuming the role.")
+	flags.StringVar(&roleSessionName, "role-session-name", "", "The session name of the assumed role.")
+	flags.StringVar(&externalID, "external-id", "", "The external ID for assuming the role.")
+	flags.StringVar(&mfaSerial, "mfa-serial", "", "The serial number or ARN of the MFA device for assuming the role. The token is prompted on the terminal.")
+}
+
+// Debug returns whether debug logging is enabled.
+func Debug() bool {
+	return debug
 }
 
 // LoadAWSConfig returns aws.Config.
@@ -38,7 +76,18 @@ func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
 		return awsConfig.Copy(), nil
 	}
 
-	opts := []func(*config.LoadOptions) error{}
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	opts := []func(*config.LoadOptions) error{
+		config.WithHTTPClient(httpClient),
+	}
+	if readTimeout > 0 {
+		opts = append(opts, config.WithAPIOptions([]func(*middleware.Stack) error{
+			addReadTimeout(time.Duration(readTimeout) * time.Second),
+		}))
+	}
 
 	if awsRegion != "" {
 		opts = append(opts, config.WithRegion(awsRegion))
@@ -46,6 +95,19 @@ func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
 	if awsProfile != "" {
 		opts = append(opts, config.WithSharedConfigProfile(awsProfile))
 	}
+	if maxAttempts < 0 {
+		return aws.Config{}, fmt.Errorf("invalid max attempts: %d", maxAttempts)
+	}
+	if maxAttempts > 0 {
+		opts = append(opts, config.WithRetryMaxAttempts(maxAttempts))
+	}
+	if retryMode != "" {
+		mode, err := aws.ParseRetryMode(retryMode)
+		if err != nil {
+			return aws.Config{}, err
+		}
+		opts = append(opts, config.WithRetryMode(mode))
+	}
 
 	// Load default config
 	cfg, err := config.LoadDefaultConfig(ctx, opts...)
@@ -53,25 +115,95 @@ func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
 		return aws.Config{}, err
 	}
 
-	if endpointURL != "" {
-		cfg.BaseEndpoint = aws.String(endpointURL)
-	}
 	if debug {
 		cfg.ClientLogMode |= aws.LogSigning | aws.LogRetries | aws.LogRequest | aws.LogRequestWithBody | aws.LogResponse | aws.LogResponseWithBody
 	}
+	if roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(newAssumeRoleProvider(cfg))
+	} else if roleSessionName != "" || externalID != "" || mfaSerial != "" {
+		return aws.Config{}, errors.New("--role-session-name, --external-id and --mfa-serial require --role-arn")
+	}
 
 	awsConfig = cfg
 	awsConfigLoaded = true
 	return awsConfig.Copy(), nil
 }
 
+// AddressingStyle returns the addressing style of S3 requests
+// from --addressing-style or the addressing_style of the s3 settings.
+func AddressingStyle() string {
+	if addressingStyle != "" {
+		return addressingStyle
+	}
+	return S3Setting("addressing_style")
+}
+
+// loadS3Options returns the options for S3 clients.
+func loadS3Options() (func(*s3.Options), error) {
+	style := AddressingStyle()
+	accelerate, err := s3BoolSetting(useAccelerateEndpoint, "use_accelerate_endpoint")
+	if err != nil {
+		return nil, err
+	}
+	dualstack, err := s3BoolSetting(useDualstackEndpoint, "use_dualstack_endpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	var pathStyle bool
+	switch style {
+	case "", "auto", "virtual":
+		// the SDK uses virtual hosted-style requests,
+		// and falls back to path-style requests if the bucket name is not compatible with DNS.
+		pathStyle = false
+	case "path":
+		pathStyle = true
+	default:
+		return nil, fmt.Errorf("invalid addressing style: %s, valid values are 'path', 'virtual' or 'auto'", style)
+	}
+	if accelerate && pathStyle {
+		return nil, fmt.Errorf("the accelerate endpoint can't be used with the path addressing style")
+	}
+
+	return func(o *s3.Options) {
+		if endpointURL != "" {
+			// the endpoint is applied only to S3, not to other services such as STS.
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+		o.UsePathStyle = pathStyle
+		o.UseAccelerate = accelerate
+		if dualstack {
+			o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+		}
+	}, nil
+}
+
+func s3BoolSetting(flag bool, key string) (bool, error) {
+	if flag {
+		return true, nil
+	}
+	v := S3Setting(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", key, v)
+	}
+	return b, nil
+}
+
 // NewS3Client returns new S3 client.
 func NewS3Client(ctx context.Context) (interfaces.S3Client, error) {
 	cfg, err := LoadAWSConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
-	svc := s3.NewFromConfig(cfg)
+	optFn, err := loadS3Options()
+	if err != nil {
+		return nil, err
+	}
+	svc := s3.NewFromConfig(cfg, optFn)
 	return svc, nil
 }
 
@@ -86,22 +218,109 @@ func NewS3ServiceClient(ctx context.Context) (interfaces.S3Client, error) {
 		// fall back to US East (N. Virginia)
 		cfg.Region = "us-east-1"
 	}
-	svc := s3.NewFromConfig(cfg)
+	optFn, err := loadS3Options()
+	if err != nil {
+		return nil, err
+	}
+	svc := s3.NewFromConfig(cfg, optFn)
 	return svc, nil
 }
 
 // NewS3BucketClient returns new S3 client that is used for the bucket.
-func NewS3BucketClient(ctx context.Context, bucket string) (interfaces.S3Client, error) {
+// optFns are applied to the returned client.
+func NewS3BucketClient(ctx context.Context, bucket string, optFns ...func(*s3.Options)) (interfaces.S3Client, error) {
 	cfg, err := LoadAWSConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
-	svc := s3.NewFromConfig(cfg)
-	region, err := manager.GetBucketRegion(ctx, svc, bucket)
+	optFn, err := loadS3Options()
+	if err != nil {
+		return nil, err
+	}
+
+	// the region is given explicitly, or the endpoint doesn't support looking up the region.
+	// the endpoint may come from --endpoint-url, AWS_ENDPOINT_URL(_S3) or endpoint_url in the profile,
+	// and the SDK resolves them all into the BaseEndpoint of the client options.
+	custom := s3.NewFromConfig(cfg, optFn).Options().BaseEndpoint != nil
+	if awsRegion != "" || custom {
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		svc := s3.NewFromConfig(cfg, append([]func(*s3.Options){optFn}, optFns...)...)
+		return svc, nil
+	}
+
+	region, err := getBucketRegion(ctx, cfg, optFn, bucket)
 	if err != nil {
 		return nil, err
 	}
 	cfg.Region = region
-	svc = s3.NewFromConfig(cfg)
+	svc := s3.NewFromConfig(cfg, append([]func(*s3.Options){optFn}, optFns...)...)
 	return svc, nil
 }
+
+// getBucketRegion returns the region of the bucket.
+// It uses the on-disk cache if available.
+func getBucketRegion(ctx context.Context, cfg aws.Config, optFn func(*s3.Options), bucket string) (string, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	svc := s3.NewFromConfig(cfg, optFn)
+
+	cache, err := newRegionCache()
+	if err != nil {
+		debugf("failed to open the bucket region cache: %v", err)
+	}
+	o := svc.Options()
+	key := regionCacheKey(aws.ToString(o.BaseEndpoint), partitionOf(o.Region), bucket)
+	if cache != nil {
+		if region, ok := cache.get(key); ok {
+			return region, nil
+		}
+	}
+
+	region, err := manager.GetBucketRegion(ctx, svc, bucket, optFn)
+	var notFound manager.BucketNotFound
+	if errors.As(err, &notFound) {
+		return "", err
+	}
+	if region == "" {
+		// the response of HeadBucket doesn't have the region header.
+		// fall back to GetBucketLocation.
+		debugf("failed to get the region of the bucket %s from HeadBucket: %v", bucket, err)
+		region, err = getBucketLocation(ctx, svc, bucket)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cache != nil {
+		if err := cache.put(key, region); err != nil {
+			debugf("failed to save the bucket region cache: %v", err)
+		}
+	}
+	return region, nil
+}
+
+func getBucketLocation(ctx context.Context, svc interfaces.S3Client, bucket string) (string, error) {
+	resp, err := svc.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return "", err
+	}
+	switch resp.LocationConstraint {
+	case "":
+		// buckets in US East (N. Virginia) have a null location constraint.
+		return "us-east-1", nil
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1", nil
+	}
+	return string(resp.LocationConstraint), nil
+}
+
+func debugf(format string, args ...any) {
+	if debug {
+		log.Printf(format, args...)
+	}
+}
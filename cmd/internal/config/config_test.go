@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestLoadS3Options(t *testing.T) {
+	t.Cleanup(func() {
+		addressingStyle = ""
+		endpointURL = ""
+		useAccelerateEndpoint = false
+		useDualstackEndpoint = false
+	})
+
+	cases := []struct {
+		style      string
+		endpoint   string
+		accelerate bool
+		dualstack  bool
+		pathStyle  bool
+	}{
+		{"", "", false, false, false},
+		{"auto", "http://localhost:9000", false, false, false},
+		{"virtual", "http://localhost:9000", false, false, false},
+		{"path", "", false, false, true},
+		{"path", "http://localhost:9000", false, false, true},
+		{"auto", "", true, true, false},
+	}
+	for _, tt := range cases {
+		addressingStyle = tt.style
+		endpointURL = tt.endpoint
+		useAccelerateEndpoint = tt.accelerate
+		useDualstackEndpoint = tt.dualstack
+
+		optFn, err := loadS3Options()
+		if err != nil {
+			t.Errorf("%q, %q: unexpected error: %v", tt.style, tt.endpoint, err)
+			continue
+		}
+		var o s3.Options
+		optFn(&o)
+		if o.UsePathStyle != tt.pathStyle {
+			t.Errorf("%q, %q: want UsePathStyle %t, got %t", tt.style, tt.endpoint, tt.pathStyle, o.UsePathStyle)
+		}
+		if o.UseAccelerate != tt.accelerate {
+			t.Errorf("%q, %q: want UseAccelerate %t, got %t", tt.style, tt.endpoint, tt.accelerate, o.UseAccelerate)
+		}
+		if got := o.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled; got != tt.dualstack {
+			t.Errorf("%q, %q: want dual-stack %t, got %t", tt.style, tt.endpoint, tt.dualstack, got)
+		}
+	}
+
+	// invalid combinations
+	addressingStyle = "path"
+	useAccelerateEndpoint = true
+	if _, err := loadS3Options(); err == nil {
+		t.Error("want error, got nil")
+	}
+	addressingStyle = "foobar"
+	useAccelerateEndpoint = false
+	if _, err := loadS3Options(); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+// newLocationServer returns the server that responds the locations of the buckets to GetBucketLocation.
+func newLocationServer(t *testing.T, locations map[string]string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// the response doesn't have the x-amz-bucket-region header.
+			return
+		}
+		if !r.URL.Query().Has("location") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>`+
+			`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">%s</LocationConstraint>`,
+			locations[r.URL.Path[1:]])
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// isolateRegionCache makes the region cache empty during the test.
+func isolateRegionCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LocalAppData", t.TempDir())
+}
+
+func endpointOption(url string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(url)
+		o.UsePathStyle = true
+	}
+}
+
+func TestGetBucketRegion_GetBucketLocation(t *testing.T) {
+	isolateRegionCache(t)
+	ts := newLocationServer(t, map[string]string{
+		"us-bucket": "",
+		"eu-bucket": "EU",
+		"jp-bucket": "ap-northeast-1",
+	})
+
+	cfg := aws.Config{
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  ts.Client(),
+	}
+	optFn := endpointOption(ts.URL)
+	want := map[string]string{
+		"us-bucket": "us-east-1",
+		"eu-bucket": "eu-west-1",
+		"jp-bucket": "ap-northeast-1",
+	}
+	for bucket, region := range want {
+		got, err := getBucketRegion(t.Context(), cfg, optFn, bucket)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", bucket, err)
+			continue
+		}
+		if got != region {
+			t.Errorf("%s: want %s, got %s", bucket, region, got)
+		}
+	}
+
+	// the regions are cached.
+	ts.Close()
+	for bucket, region := range want {
+		got, err := getBucketRegion(t.Context(), cfg, optFn, bucket)
+		if err != nil || got != region {
+			t.Errorf("%s: want %s, got %s, %v", bucket, region, got, err)
+		}
+	}
+}
+
+func TestGetBucketRegion_SameBucketOnOtherEndpoints(t *testing.T) {
+	isolateRegionCache(t)
+	ts1 := newLocationServer(t, map[string]string{"bucket": "ap-northeast-1"})
+	ts2 := newLocationServer(t, map[string]string{"bucket": "us-west-2"})
+
+	cfg := aws.Config{
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  http.DefaultClient,
+	}
+	// run twice, the second run uses the cache.
+	for range 2 {
+		if got, err := getBucketRegion(t.Context(), cfg, endpointOption(ts1.URL), "bucket"); err != nil || got != "ap-northeast-1" {
+			t.Errorf("want ap-northeast-1, got %s, %v", got, err)
+		}
+		if got, err := getBucketRegion(t.Context(), cfg, endpointOption(ts2.URL), "bucket"); err != nil || got != "us-west-2" {
+			t.Errorf("want us-west-2, got %s, %v", got, err)
+		}
+	}
+}
+
+func TestNewS3BucketClient_EndpointFromEnv(t *testing.T) {
+	t.Cleanup(func() { awsConfigLoaded = false })
+	isolateRegionCache(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", ts.URL)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_REGION", "")
+
+	// the region of the bucket on the custom endpoint is not looked up.
+	awsConfigLoaded = false
+	if _, err := NewS3BucketClient(t.Context(), "bucket"); err != nil {
+		t.Fatal(err)
+	}
+}
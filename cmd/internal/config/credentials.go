@@ -0,0 +1,182 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// credentialsCacheWindow is the duration before the expiration
+// that the cached credentials are treated as expired.
+const credentialsCacheWindow = 5 * time.Minute
+
+// mfaTokenProvider prompts for the MFA token.
+// It is a variable, because of tests.
+var mfaTokenProvider = ttyTokenProvider
+
+// newAssumeRoleProvider returns the credentials provider that assumes the role given by --role-arn.
+// cfg is used for calling AssumeRole API.
+func newAssumeRoleProvider(cfg aws.Config) aws.CredentialsProvider {
+	sessionName := roleSessionName
+	if sessionName == "" {
+		sessionName = "s3cli-mini-" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	svc := sts.NewFromConfig(cfg)
+	provider := stscreds.NewAssumeRoleProvider(svc, roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+		if mfaSerial != "" {
+			o.SerialNumber = aws.String(mfaSerial)
+			o.TokenProvider = func() (string, error) {
+				return mfaTokenProvider(mfaSerial)
+			}
+		}
+	})
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		// caching is not available. use the provider directly.
+		return provider
+	}
+	return &fileCacheProvider{
+		provider: provider,
+		path:     filepath.Join(dir, "s3cli-mini", "credentials", assumeRoleCacheKey()+".json"),
+		now:      time.Now,
+	}
+}
+
+// assumeRoleCacheKey returns the key of the credentials cache.
+// It doesn't contain the session name generated automatically,
+// so the credentials can be reused across the invocations.
+func assumeRoleCacheKey() string {
+	data, _ := json.Marshal(map[string]string{
+		"profile":           Profile(),
+		"role_arn":          roleARN,
+		"role_session_name": roleSessionName,
+		"external_id":       externalID,
+		"mfa_serial":        mfaSerial,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileCacheProvider caches the credentials on the disk, same as ~/.aws/cli/cache of the AWS CLI.
+type fileCacheProvider struct {
+	provider aws.CredentialsProvider
+	path     string
+	now      func() time.Time
+}
+
+type cachedCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (p *fileCacheProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	if creds, ok := p.load(); ok {
+		return creds, nil
+	}
+
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if creds.CanExpire {
+		if err := p.save(creds); err != nil {
+			debugf("failed to save the credentials cache: %v", err)
+		}
+	}
+	return creds, nil
+}
+
+func (p *fileCacheProvider) load() (aws.Credentials, bool) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return aws.Credentials{}, false
+	}
+	var cached cachedCredentials
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return aws.Credentials{}, false
+	}
+	if !p.now().Add(credentialsCacheWindow).Before(cached.Expiration) {
+		return aws.Credentials{}, false
+	}
+	return aws.Credentials{
+		AccessKeyID:     cached.AccessKeyID,
+		SecretAccessKey: cached.SecretAccessKey,
+		SessionToken:    cached.SessionToken,
+		Source:          "s3cli-mini credentials cache",
+		CanExpire:       true,
+		Expires:         cached.Expiration,
+	}, true
+}
+
+func (p *fileCacheProvider) save(creds aws.Credentials) error {
+	data, err := json.Marshal(cachedCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expires,
+	})
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(p.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "credentials-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p.path)
+}
+
+// ttyTokenProvider prompts for the MFA token on the terminal.
+// It doesn't use STDIN, because STDIN may be the source of the upload.
+func ttyTokenProvider(serial string) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", fmt.Errorf("failed to open the terminal for the MFA token: %w", err)
+	}
+	defer tty.Close()
+
+	if _, err := fmt.Fprintf(tty, "Enter MFA code for %s: ", serial); err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("the MFA token is empty")
+	}
+	return token, nil
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// stubSTS is a stub of STS and SSO endpoints.
+type stubSTS struct {
+	mu       sync.Mutex
+	requests []map[string]string
+}
+
+func (s *stubSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/federation/credentials" {
+		// SSO GetRoleCredentials
+		if r.Header.Get("X-Amz-Sso_bearer_token") != "sso-access-token" {
+			http.Error(w, `{"message":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"roleCredentials":{"accessKeyId":"AKID-SSO-%s","secretAccessKey":"secret","sessionToken":"token","expiration":4102444800000}}`, r.URL.Query().Get("role_name"))
+		return
+	}
+
+	// STS AssumeRole
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := map[string]string{}
+	for k := range r.PostForm {
+		req[k] = r.PostForm.Get(k)
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>AKID-ASSUMED</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>%s/%s</Arn>
+      <AssumedRoleId>AROA:%s</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+  <ResponseMetadata><RequestId>request-id</RequestId></ResponseMetadata>
+</AssumeRoleResponse>`, req["RoleArn"], req["RoleSessionName"], req["RoleSessionName"])
+}
+
+func (s *stubSTS) Requests() []map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]string(nil), s.requests...)
+}
+
+// setupCredentialsTest isolates the test from the user's environment,
+// and starts the stub STS endpoint.
+func setupCredentialsTest(t *testing.T, config string) *stubSTS {
+	t.Helper()
+
+	stub := &stubSTS{}
+	ts := httptest.NewServer(stub)
+	t.Cleanup(ts.Close)
+
+	home := t.TempDir()
+	configFile := filepath.Join(home, "config")
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentialsFile := filepath.Join(home, "credentials")
+	if err := os.WriteFile(credentialsFile, []byte("[source]\naws_access_key_id = AKID-SOURCE\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, env := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_ENDPOINT_URL", "AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE",
+	} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	t.Setenv("LocalAppData", filepath.Join(home, "cache"))
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ENDPOINT_URL_STS", ts.URL)
+	t.Setenv("AWS_ENDPOINT_URL_SSO", ts.URL)
+
+	resetAWSConfig()
+	t.Cleanup(func() {
+		resetAWSConfig()
+		awsProfile = ""
+		roleARN = ""
+		roleSessionName = ""
+		externalID = ""
+		mfaSerial = ""
+		mfaTokenProvider = ttyTokenProvider
+	})
+	return stub
+}
+
+func resetAWSConfig() {
+	mu.Lock()
+	defer mu.Unlock()
+	awsConfigLoaded = false
+	awsConfig = aws.Config{}
+}
+
+func retrieveCredentials(t *testing.T) aws.Credentials {
+	t.Helper()
+	cfg, err := LoadAWSConfig(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := cfg.Credentials.Retrieve(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creds
+}
+
+func TestAssumeRole_Flags(t *testing.T) {
+	stub := setupCredentialsTest(t, "")
+
+	awsProfile = "source"
+	roleARN = "arn:aws:iam::123456789012:role/s3cli-mini"
+	roleSessionName = "session"
+	externalID = "external-id"
+	mfaSerial = "arn:aws:iam::123456789012:mfa/user"
+	mfaTokenProvider = func(serial string) (string, error) {
+		return "123456", nil
+	}
+
+	creds := retrieveCredentials(t)
+	if creds.AccessKeyID != "AKID-ASSUMED" {
+		t.Errorf("want AKID-ASSUMED, got %s", creds.AccessKeyID)
+	}
+
+	requests := stub.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("want 1 request, got %d", len(requests))
+	}
+	want := map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         "arn:aws:iam::123456789012:role/s3cli-mini",
+		"RoleSessionName": "session",
+		"ExternalId":      "external-id",
+		"SerialNumber":    "arn:aws:iam::123456789012:mfa/user",
+		"TokenCode":       "123456",
+	}
+	for k, v := range want {
+		if requests[0][k] != v {
+			t.Errorf("%s: want %q, got %q", k, v, requests[0][k])
+		}
+	}
+
+	// the credentials are cached across the invocations.
+	resetAWSConfig()
+	mfaTokenProvider = func(serial string) (string, error) {
+		t.Error("the MFA token must not be prompted")
+		return "", nil
+	}
+	creds = retrieveCredentials(t)
+	if creds.AccessKeyID != "AKID-ASSUMED" {
+		t.Errorf("want AKID-ASSUMED, got %s", creds.AccessKeyID)
+	}
+	if n := len(stub.Requests()); n != 1 {
+		t.Errorf("want 1 request, got %d", n)
+	}
+}
+
+func TestAssumeRole_RequiresRoleARN(t *testing.T) {
+	setupCredentialsTest(t, "")
+
+	externalID = "external-id"
+	if _, err := LoadAWSConfig(t.Context()); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestAssumeRole_SourceProfile(t *testing.T) {
+	stub := setupCredentialsTest(t, `
+[profile role]
+role_arn = arn:aws:iam::123456789012:role/from-profile
+source_profile = source
+`)
+
+	awsProfile = "role"
+	creds := retrieveCredentials(t)
+	if creds.AccessKeyID != "AKID-ASSUMED" {
+		t.Errorf("want AKID-ASSUMED, got %s", creds.AccessKeyID)
+	}
+	requests := stub.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("want 1 request, got %d", len(requests))
+	}
+	if got := requests[0]["RoleArn"]; got != "arn:aws:iam::123456789012:role/from-profile" {
+		t.Errorf("unexpected role arn: %s", got)
+	}
+}
+
+func TestCredentialProcess(t *testing.T) {
+	setupCredentialsTest(t, fmt.Sprintf(`
+[profile process]
+credential_process = "%s" -test.run=TestHelperCredentialProcess
+`, os.Args[0]))
+	t.Setenv("S3CLI_TEST_CREDENTIAL_PROCESS", "1")
+
+	awsProfile = "process"
+	creds := retrieveCredentials(t)
+	if creds.AccessKeyID != "AKID-PROCESS" {
+		t.Errorf("want AKID-PROCESS, got %s", creds.AccessKeyID)
+	}
+}
+
+// TestHelperCredentialProcess isn't a real test.
+// It's used as a credential_process by TestCredentialProcess.
+func TestHelperCredentialProcess(t *testing.T) {
+	if os.Getenv("S3CLI_TEST_CREDENTIAL_PROCESS") != "1" {
+		return
+	}
+	fmt.Print(`{"Version":1,"AccessKeyId":"AKID-PROCESS","SecretAccessKey":"secret","SessionToken":"token","Expiration":"2100-01-01T00:00:00Z"}`)
+	os.Exit(0)
+}
+
+func TestSSOSession(t *testing.T) {
+	setupCredentialsTest(t, `
+[profile sso]
+sso_session = my-sso
+sso_account_id = 123456789012
+sso_role_name = ReadOnly
+
+[sso-session my-sso]
+sso_region = us-east-1
+sso_start_url = https://example.awsapps.com/start
+`)
+
+	// prepare the token cache, as if `aws sso login` is done.
+	sum := sha1.Sum([]byte("my-sso"))
+	cacheDir := filepath.Join(os.Getenv("HOME"), ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	token := `{"accessToken":"sso-access-token","expiresAt":"2100-01-01T00:00:00Z","region":"us-east-1","startUrl":"https://example.awsapps.com/start"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	awsProfile = "sso"
+	creds := retrieveCredentials(t)
+	if creds.AccessKeyID != "AKID-SSO-ReadOnly" {
+		t.Errorf("want AKID-SSO-ReadOnly, got %s", creds.AccessKeyID)
+	}
+}
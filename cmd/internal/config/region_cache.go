@@ -0,0 +1,143 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// regionCacheTTL is the lifetime of the cached bucket regions.
+const regionCacheTTL = 24 * time.Hour
+
+// regionCache is an on-disk cache of the bucket regions.
+type regionCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+type regionCacheEntry struct {
+	Region  string    `json:"region"`
+	Expires time.Time `json:"expires"`
+}
+
+// newRegionCache returns the region cache in the user's cache directory.
+func newRegionCache() (*regionCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &regionCache{
+		path: filepath.Join(dir, "s3cli-mini", "bucket-regions.json"),
+		ttl:  regionCacheTTL,
+		now:  time.Now,
+	}, nil
+}
+
+// regionCacheKey returns the key of the cache for the bucket.
+// The buckets with the same name may exist on other endpoints and in other partitions, e.g. aws-cn.
+func regionCacheKey(endpoint, partition, bucket string) string {
+	if endpoint == "" {
+		endpoint = "default"
+	}
+	return endpoint + " " + partition + " " + bucket
+}
+
+// partitionOf returns the AWS partition of the region.
+func partitionOf(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	case strings.HasPrefix(region, "us-isof-"):
+		return "aws-iso-f"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	case strings.HasPrefix(region, "eu-isoe-"):
+		return "aws-iso-e"
+	}
+	return "aws"
+}
+
+// get returns the cached region.
+func (c *regionCache) get(key string) (string, bool) {
+	entries, err := c.load()
+	if err != nil {
+		return "", false
+	}
+	entry, ok := entries[key]
+	if !ok || !c.now().Before(entry.Expires) {
+		return "", false
+	}
+	return entry.Region, true
+}
+
+// put saves the region into the cache.
+func (c *regionCache) put(key, region string) error {
+	entries, err := c.load()
+	if err != nil {
+		// the cache is broken. rebuild it.
+		entries = map[string]regionCacheEntry{}
+	}
+
+	// remove expired entries
+	now := c.now()
+	for k, entry := range entries {
+		if !now.Before(entry.Expires) {
+			delete(entries, k)
+		}
+	}
+	entries[key] = regionCacheEntry{
+		Region:  region,
+		Expires: now.Add(c.ttl),
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// write the cache atomically, because other processes may read it concurrently.
+	f, err := os.CreateTemp(dir, "bucket-regions-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path)
+}
+
+func (c *regionCache) load() (map[string]regionCacheEntry, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]regionCacheEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]regionCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = map[string]regionCacheEntry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegionCache(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := &regionCache{
+		path: filepath.Join(t.TempDir(), "s3cli-mini", "bucket-regions.json"),
+		ttl:  time.Hour,
+		now:  func() time.Time { return now },
+	}
+
+	key := regionCacheKey("", "aws", "example-bucket")
+	if _, ok := cache.get(key); ok {
+		t.Error("want cache miss, got hit")
+	}
+
+	if err := cache.put(key, "ap-northeast-1"); err != nil {
+		t.Fatal(err)
+	}
+	if region, ok := cache.get(key); !ok || region != "ap-northeast-1" {
+		t.Errorf("want ap-northeast-1, got %q, %t", region, ok)
+	}
+
+	// the cache is keyed by the endpoint and the partition.
+	if _, ok := cache.get(regionCacheKey("http://localhost:9000", "aws", "example-bucket")); ok {
+		t.Error("want cache miss, got hit")
+	}
+	if _, ok := cache.get(regionCacheKey("", "aws-cn", "example-bucket")); ok {
+		t.Error("want cache miss, got hit")
+	}
+
+	// the cache expires.
+	now = now.Add(time.Hour)
+	if _, ok := cache.get(key); ok {
+		t.Error("want cache miss, got hit")
+	}
+}
+
+func TestRegionCache_Broken(t *testing.T) {
+	cache := &regionCache{
+		path: filepath.Join(t.TempDir(), "bucket-regions.json"),
+		ttl:  time.Hour,
+		now:  time.Now,
+	}
+	if err := os.WriteFile(cache.path, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key := regionCacheKey("", "aws", "example-bucket")
+	if _, ok := cache.get(key); ok {
+		t.Error("want cache miss, got hit")
+	}
+	if err := cache.put(key, "us-west-2"); err != nil {
+		t.Fatal(err)
+	}
+	if region, ok := cache.get(key); !ok || region != "us-west-2" {
+		t.Errorf("want us-west-2, got %q, %t", region, ok)
+	}
+}
+
+func TestPartitionOf(t *testing.T) {
+	cases := map[string]string{
+		"us-east-1":      "aws",
+		"ap-northeast-1": "aws",
+		"cn-north-1":     "aws-cn",
+		"us-gov-west-1":  "aws-us-gov",
+		"us-iso-east-1":  "aws-iso",
+		"us-isob-east-1": "aws-iso-b",
+	}
+	for region, want := range cases {
+		if got := partitionOf(region); got != want {
+			t.Errorf("%s: want %s, got %s", region, want, got)
+		}
+	}
+}
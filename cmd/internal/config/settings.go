@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shogo82148/s3cli-mini/transfer"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultMaxConcurrentRequests is the default number of concurrent requests.
+	DefaultMaxConcurrentRequests = transfer.DefaultConcurrency
+
+	// DefaultMultipartThreshold is the default size threshold for multipart transfers.
+	DefaultMultipartThreshold = transfer.DefaultMultipartThreshold
+
+	// DefaultMultipartChunkSize is the default chunk size for multipart transfers.
+	DefaultMultipartChunkSize = transfer.DefaultMultipartChunkSize
+
+	// minMultipartChunkSize is the minimum part size that S3 accepts.
+	minMultipartChunkSize = 5 * 1024 * 1024
+)
+
+// EnvPrefix is the prefix of the environment values that override the config file.
+const EnvPrefix = "S3CLI"
+
+// Profile returns the name of the profile in use.
+func Profile() string {
+	if awsProfile != "" {
+		return awsProfile
+	}
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// S3Setting returns the value of the s3 setting in the config file.
+// The settings are looked up in the following order:
+//
+//   - S3CLI_S3_<KEY> environment value
+//   - profiles.<profile>.s3.<key>
+//   - s3.<key>
+//
+// The keys are same as the s3 section of ~/.aws/config.
+// https://docs.aws.amazon.com/cli/latest/topic/s3-config.html
+func S3Setting(key string) string {
+	if v := os.Getenv(EnvPrefix + "_S3_" + strings.ToUpper(key)); v != "" {
+		return v
+	}
+	if v := viper.GetString("profiles." + Profile() + ".s3." + key); v != "" {
+		return v
+	}
+	return viper.GetString("s3." + key)
+}
+
+// MaxConcurrentRequests returns the maximum number of concurrent requests.
+func MaxConcurrentRequests() (int, error) {
+	v := S3Setting("max_concurrent_requests")
+	if v == "" {
+		return DefaultMaxConcurrentRequests, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid max_concurrent_requests: %q", v)
+	}
+	return n, nil
+}
+
+// MultipartThreshold returns the size threshold for multipart transfers.
+func MultipartThreshold() (int64, error) {
+	v := S3Setting("multipart_threshold")
+	if v == "" {
+		return DefaultMultipartThreshold, nil
+	}
+	n, err := ParseSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid multipart_threshold: %w", err)
+	}
+	return n, nil
+}
+
+// MultipartChunkSize returns the chunk size for multipart transfers.
+func MultipartChunkSize() (int64, error) {
+	v := S3Setting("multipart_chunksize")
+	if v == "" {
+		return DefaultMultipartChunkSize, nil
+	}
+	n, err := ParseSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid multipart_chunksize: %w", err)
+	}
+	if n < minMultipartChunkSize {
+		return 0, fmt.Errorf("invalid multipart_chunksize: %q is less than 5MB", v)
+	}
+	return n, nil
+}
+
+// ParseSize parses the size string, such as "1024", "8MB", and "1GiB".
+// Same as the AWS CLI, the suffixes are base 2. i.e. 1KB = 1KiB = 1024 bytes.
+func ParseSize(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+	upper := strings.ToUpper(str)
+	multiplier := int64(1)
+	for _, suffix := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(upper, suffix.suffix) {
+			str = strings.TrimSpace(str[:len(str)-len(suffix.suffix)])
+			multiplier = suffix.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	if n > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("size is too large: %q", s)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		in  string
+		out int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"10B", 10},
+		{"8KB", 8 << 10},
+		{"8MB", 8 << 20},
+		{"8mb", 8 << 20},
+		{"8 MiB", 8 << 20},
+		{"1G", 1 << 30},
+		{"1GiB", 1 << 30},
+		{"2TB", 2 << 40},
+	}
+	for _, tt := range cases {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.out {
+			t.Errorf("%q: want %d, got %d", tt.in, tt.out, got)
+		}
+	}
+
+	for _, in := range []string{"", "MB", "-1", "1.5MB", "1PB", "9999999999TB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("%q: want error, got nil", in)
+		}
+	}
+}
+
+func TestS3Setting(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { awsProfile = "" })
+
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+s3:
+  max_concurrent_requests: 10
+  multipart_chunksize: 16MB
+profiles:
+  foo:
+    s3:
+      max_concurrent_requests: 20
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsProfile = "foo"
+	if n, err := MaxConcurrentRequests(); err != nil || n != 20 {
+		t.Errorf("want 20, got %d, %v", n, err)
+	}
+	if n, err := MultipartChunkSize(); err != nil || n != 16<<20 {
+		t.Errorf("want %d, got %d, %v", 16<<20, n, err)
+	}
+	if n, err := MultipartThreshold(); err != nil || n != DefaultMultipartThreshold {
+		t.Errorf("want %d, got %d, %v", DefaultMultipartThreshold, n, err)
+	}
+
+	awsProfile = "bar"
+	if n, err := MaxConcurrentRequests(); err != nil || n != 10 {
+		t.Errorf("want 10, got %d, %v", n, err)
+	}
+}
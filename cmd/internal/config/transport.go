@@ -0,0 +1,157 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// newHTTPClient returns the HTTP client configured by the transport flags.
+func newHTTPClient() (*awshttp.BuildableClient, error) {
+	if connectTimeout < 0 {
+		return nil, fmt.Errorf("invalid connect timeout: %d", connectTimeout)
+	}
+	if readTimeout < 0 {
+		return nil, fmt.Errorf("invalid read timeout: %d", readTimeout)
+	}
+
+	var rootCAs *x509.CertPool
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle: %w", err)
+		}
+		rootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("failed to load the CA bundle: no certificates found")
+		}
+	}
+
+	n, err := MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(connectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	timeout := time.Duration(readTimeout) * time.Second
+
+	client := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		// honor HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+		tr.Proxy = http.ProxyFromEnvironment
+		tr.DialContext = dialer.DialContext
+
+		// the read timeout starts after the request body is sent,
+		// because sending a large part on a slow link may take longer than the timeout.
+		tr.ResponseHeaderTimeout = timeout
+
+		// keep enough idle connections for the concurrent requests.
+		tr.MaxIdleConnsPerHost = max(tr.MaxIdleConnsPerHost, n)
+		tr.MaxIdleConns = max(tr.MaxIdleConns, tr.MaxIdleConnsPerHost)
+
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		if rootCAs != nil {
+			tr.TLSClientConfig.RootCAs = rootCAs
+		}
+		if noVerifySSL {
+			tr.TLSClientConfig.InsecureSkipVerify = true
+		}
+	})
+	return client, nil
+}
+
+// addReadTimeout returns the API option that fails reading the response body
+// if no data is arrived within the timeout.
+// It is applied to the response body instead of the HTTP client,
+// because the SDK configures only *awshttp.BuildableClient, e.g. for AWS_CA_BUNDLE.
+func addReadTimeout(timeout time.Duration) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		// add it at the end of the deserialize step, so it wraps the body before the deserializers read it.
+		return stack.Deserialize.Add(&readTimeoutMiddleware{timeout: timeout}, middleware.After)
+	}
+}
+
+// readTimeoutMiddleware wraps the response body with readTimeoutBody.
+type readTimeoutMiddleware struct {
+	timeout time.Duration
+}
+
+func (m *readTimeoutMiddleware) ID() string {
+	return "S3CLIReadTimeout"
+}
+
+func (m *readTimeoutMiddleware) HandleDeserialize(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (
+	out middleware.DeserializeOutput, metadata middleware.Metadata, err error,
+) {
+	out, metadata, err = next.HandleDeserialize(ctx, in)
+	if err != nil {
+		return out, metadata, err
+	}
+	if resp, ok := out.RawResponse.(*smithyhttp.Response); ok && resp.Body != nil {
+		resp.Body = newReadTimeoutBody(resp.Body, m.timeout)
+	}
+	return out, metadata, nil
+}
+
+// readTimeoutBody is a response body that is closed if a Read is blocked longer than the timeout.
+// The time between Reads is not limited, so slow consumers don't fail.
+type readTimeoutBody struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newReadTimeoutBody(body io.ReadCloser, timeout time.Duration) *readTimeoutBody {
+	b := &readTimeoutBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		b.body.Close()
+	})
+	b.timer.Stop()
+	return b
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.body.Read(p)
+	b.timer.Stop()
+	if err != nil && b.timedOut.Load() {
+		err = errReadTimeout
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
+
+var errReadTimeout net.Error = readTimeoutError{}
+
+// readTimeoutError is the error of readTimeoutBody.
+// It is a net.Error, so the SDK retries the request.
+type readTimeoutError struct{}
+
+func (readTimeoutError) Error() string   { return "read timeout: no data arrived within the timeout" }
+func (readTimeoutError) Timeout() bool   { return true }
+func (readTimeoutError) Temporary() bool { return true }
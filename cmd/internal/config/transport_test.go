@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+func TestNewHTTPClient_CABundle(t *testing.T) {
+	t.Cleanup(func() { caBundle = "" })
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// without the CA bundle, the certificate of the test server is not trusted.
+	client, err := newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(mustNewRequest(t, ts.URL)); err == nil {
+		t.Error("want error, got nil")
+	}
+
+	// with the CA bundle
+	filename := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: ts.Certificate().Raw,
+	})
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	caBundle = filename
+	client, err = newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(mustNewRequest(t, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewHTTPClient_NoVerifySSL(t *testing.T) {
+	t.Cleanup(func() { noVerifySSL = false })
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	noVerifySSL = true
+	client, err := newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(mustNewRequest(t, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewHTTPClient_ReadTimeout(t *testing.T) {
+	t.Cleanup(func() { readTimeout = 0 })
+
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	readTimeout = 1
+	client, err := newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(mustNewRequest(t, ts.URL))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("want timeout error, got %v", err)
+	}
+}
+
+func TestNewHTTPClient_ReadTimeout_SlowRequestBody(t *testing.T) {
+	t.Cleanup(func() { readTimeout = 0 })
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server responds after reading the whole body.
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	readTimeout = 1
+	client, err := newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sending the body takes longer than the read timeout.
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPut, ts.URL, &slowReader{n: 6, interval: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestAddReadTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	svc := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(ts.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+		APIOptions:   []func(*middleware.Stack) error{addReadTimeout(time.Second)},
+	})
+	resp, err := svc.GetObject(t.Context(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	_, err = io.ReadAll(resp.Body)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("want timeout error, got %v", err)
+	}
+}
+
+func TestLoadAWSConfig_CABundle(t *testing.T) {
+	t.Cleanup(func() {
+		readTimeout = 0
+		awsConfigLoaded = false
+	})
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	filename := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: ts.Certificate().Raw,
+	})
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CA_BUNDLE", filename)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	// the default of --cli-read-timeout.
+	readTimeout = 60
+	awsConfigLoaded = false
+	cfg, err := LoadAWSConfig(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the CA bundle is applied to the HTTP client.
+	resp, err := cfg.HTTPClient.Do(mustNewRequest(t, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+// slowReader returns n bytes one by one with the interval.
+type slowReader struct {
+	n        int
+	interval time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.interval)
+	r.n--
+	p[0] = 'a'
+	return 1, nil
+}
+
+func mustNewRequest(t *testing.T, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
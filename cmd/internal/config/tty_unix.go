@@ -0,0 +1,12 @@
+//go:build !windows
+
+package config
+
+import (
+	"io"
+	"os"
+)
+
+func openTTY() (io.ReadWriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+package config
+
+import (
+	"io"
+	"os"
+)
+
+type console struct {
+	in  *os.File
+	out *os.File
+}
+
+func (c *console) Read(p []byte) (int, error) {
+	return c.in.Read(p)
+}
+
+func (c *console) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+func (c *console) Close() error {
+	err1 := c.in.Close()
+	err2 := c.out.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+func openTTY() (io.ReadWriteCloser, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return &console{in: in, out: out}, nil
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
-	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -66,7 +65,7 @@ func TestCP_Upload(t *testing.T) {
 	}
 
 	// test
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{filename, "s3://" + bucket.Name() + "/tmpfile.html"})
 
 	resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
@@ -134,7 +133,7 @@ func TestCP_Upload_Multipart(t *testing.T) {
 	}
 
 	// test
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{filename, "s3://" + bucket.Name() + "/tmpfile.html"})
 
 	resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
@@ -202,7 +201,7 @@ func TestCP_Upload_KeyOmitted(t *testing.T) {
 	}
 
 	// test
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{filename, "s3://" + bucket.Name()})
 
 	resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
@@ -223,9 +222,7 @@ func TestCP_Upload_KeyOmitted(t *testing.T) {
 }
 
 func TestCP_UploadPublicACL(t *testing.T) {
-	// This test overwrites the global variable `acl`.
-	// So, this test must be run in parallel.
-	// t.Parallel()
+	t.Parallel()
 
 	testutils.SkipIfUnitTest(t)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -267,11 +264,7 @@ func TestCP_UploadPublicACL(t *testing.T) {
 	}
 
 	// test
-	acl = "public-read"
-	defer func() {
-		acl = ""
-	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t, "--acl", "public-read")
 	Run(cmd, []string{filename, "s3://" + bucket.Name() + "/tmpfile"})
 
 	resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
@@ -313,9 +306,7 @@ func TestCP_UploadPublicACL(t *testing.T) {
 }
 
 func TestCP_Upload_recursive(t *testing.T) {
-	// This test overwrites the global variable `recursive`.
-	// So, this test must be run in parallel.
-	// t.Parallel()
+	t.Parallel()
 
 	testutils.SkipIfUnitTest(t)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -362,11 +353,7 @@ func TestCP_Upload_recursive(t *testing.T) {
 	}
 
 	// test
-	recursive = true
-	defer func() {
-		recursive = false
-	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t, "--recursive")
 	Run(cmd, []string{dir, "s3://" + bucket.Name()})
 
 	// check body
@@ -423,7 +410,7 @@ func TestCP_Download(t *testing.T) {
 	}
 	defer os.RemoveAll(dir)
 	filename := filepath.Join(dir, "tmpfile")
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{"s3://" + bucket.Name() + "/tmpfile", filename})
 
 	got, err := os.ReadFile(filename)
@@ -436,9 +423,7 @@ func TestCP_Download(t *testing.T) {
 }
 
 func TestCP_Download_recursive(t *testing.T) {
-	// This test overwrites the global variable `recursive`.
-	// So, this test must be run in parallel.
-	// t.Parallel()
+	t.Parallel()
 
 	testutils.SkipIfUnitTest(t)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -485,11 +470,7 @@ func TestCP_Download_recursive(t *testing.T) {
 	}
 
 	// test
-	recursive = true
-	defer func() {
-		recursive = false
-	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t, "--recursive")
 	Run(cmd, []string{"s3://" + bucket.Name() + "/", dir})
 
 	for _, key := range keys {
@@ -531,7 +512,7 @@ func TestCP_Copy(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{"s3://" + bucket.Name() + "/tmpfile", "s3://" + bucket.Name() + "/tmpfile.copy"})
 
 	// check body
@@ -587,7 +568,7 @@ func TestCP_CopyMultipart(t *testing.T) {
 	defer func() {
 		maxCopyObjectBytes = original
 	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{"s3://" + bucket.Name() + "/tmpfile", "s3://" + bucket.Name() + "/tmpfile.copy"})
 
 	// check body
@@ -609,9 +590,7 @@ func TestCP_CopyMultipart(t *testing.T) {
 }
 
 func TestCP_CopyRecursive(t *testing.T) {
-	// This test overwrites the global variable `recursive` and `maxCopyObjectBytes`.
-	// So, this test must be run in parallel.
-	// t.Parallel()
+	t.Parallel()
 
 	testutils.SkipIfUnitTest(t)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -658,13 +637,7 @@ func TestCP_CopyRecursive(t *testing.T) {
 	}
 
 	// test
-	original := maxCopyObjectBytes
-	recursive = true
-	defer func() {
-		recursive = false
-		maxCopyObjectBytes = original
-	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t, "--recursive")
 	Run(cmd, []string{"s3://" + bucket.Name() + "/foo", "s3://" + bucket.Name() + "/fizz"})
 
 	// check body
@@ -697,7 +670,7 @@ func TestCP_CopyRecursive(t *testing.T) {
 }
 
 func TestCP_CopyRecursiveMultipart(t *testing.T) {
-	// This test overwrites the global variable `recursive` and `maxCopyObjectBytes`.
+	// This test overwrites the global variable `maxCopyObjectBytes`.
 	// So, this test must be run in parallel.
 	// t.Parallel()
 
@@ -747,12 +720,10 @@ func TestCP_CopyRecursiveMultipart(t *testing.T) {
 
 	original := maxCopyObjectBytes
 	maxCopyObjectBytes = 5 * 1024 * 1024
-	recursive = true
 	defer func() {
 		maxCopyObjectBytes = original
-		recursive = false
 	}()
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t, "--recursive")
 	Run(cmd, []string{"s3://" + bucket.Name() + "/foo", "s3://" + bucket.Name() + "/fizz"})
 
 	// check body
@@ -838,7 +809,7 @@ func TestCP_DownloadToStdout(t *testing.T) {
 	defer func() { os.Stdout = origStdout }() // restore stdout
 	os.Stdout = w
 
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{"s3://" + bucket.Name() + "/tmpfile", "-"})
 	w.Close()
 
@@ -883,7 +854,7 @@ func TestCP_UploadFromStdin(t *testing.T) {
 	defer func() { os.Stdin = origStdin }() // restore stdin
 	os.Stdin = r
 
-	cmd := &cobra.Command{}
+	cmd := newTestCommand(t)
 	Run(cmd, []string{"-", "s3://" + bucket.Name() + "/tmpfile"})
 
 	// check body
@@ -0,0 +1,237 @@
+package cp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shogo82148/s3cli-mini/cmd/internal/acl"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/retention"
+	"github.com/shogo82148/s3cli-mini/transfer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+	flags.StringArray("include", []string{}, "Don't exclude files or objects in the command that match the specified pattern. See Use of Exclude and Include Filters for details.")
+	flags.StringArray("exclude", []string{}, "Exclude all files or objects from the command that matches the specified pattern.")
+	flags.String("acl", "", "Sets the ACL for the object when the command is performed.")
+	flags.Bool("recursive", false, "Command is performed on all files or objects under the specified directory or prefix.")
+	flags.Bool("follow-symlinks", true, "Symbolic links are followed only when uploading to S3 from the local filesystem.")
+	flags.Bool("no-follow-symlinks", false, "Symbolic links are skipped. It is the same as --symlinks skip.")
+	flags.String("symlinks", "", "The handling of symbolic links, follow, skip or preserve. preserve uploads the links as empty objects with the link targets, and recreates the links on download. (default follow)")
+	flags.Bool("allow-symlink-escape", false, "With --symlinks preserve, allows the downloaded links to point outside of the destination, and the files to be downloaded through the existing links to outside of it. By default, they are skipped.")
+	flags.Bool("preserve", false, "Preserves the modification times, the modes, the owners and the groups of the files, by storing them as the user metadata of the objects on upload, and restoring them on download.")
+	flags.Bool("no-guess-mime-type", false, "Do not try to guess the mime type for uploaded files. By default the mime type of a file is guessed when it is uploaded.")
+	flags.String("content-type", "", "Specify an explicit content type for this operation. This value overrides any guessed mime types.")
+	flags.String("cache-control", "", "Specifies caching behavior along the request/reply chain.")
+	flags.String("content-disposition", "", "Specifies presentational information for the object.")
+	flags.String("content-encoding", "", "Specifies what content encodings have been applied to the object and thus what decoding mechanisms must be applied to obtain the media-type referenced by the Content-Type header field.")
+	flags.String("content-language", "", "The language the content is in.")
+	flags.String("expires", "", "The date and time at which the object is no longer cacheable.")
+	flags.String("part-size", "", "The size of each part for downloading, e.g. 8MB. (default 8MB)")
+	flags.Int("part-concurrency", 0, "The number of parts downloaded concurrently for each object. (default 5)")
+	flags.String("website-redirects", "", "The file of the website redirects. Each line has the path relative to the destination and the redirect location. Only for recursive uploads.")
+	flags.String("object-lock-mode", "", "The Object Lock mode of the uploaded or copied objects, GOVERNANCE or COMPLIANCE.")
+	flags.String("object-lock-retain-until-date", "", "The date until which the uploaded or copied objects are locked, in RFC 3339 format. e.g. 2030-01-02T03:04:05Z")
+	flags.String("object-lock-legal-hold-status", "", "The legal hold status of the uploaded or copied objects, ON or OFF.")
+	flags.Bool("no-overwrite", false, "Uploads or copies the objects only if they don't exist. The existing objects are skipped.")
+	flags.String("if-match", "", "Uploads or copies the objects only if their ETags match the value. The other objects are skipped.")
+	flags.String("max-memory", "", "The maximum memory for reassembling the parts when downloading to STDOUT, e.g. 64MB. (default 64MB)")
+}
+
+// options is the options of an invocation of cp command.
+type options struct {
+	recursive bool
+	transfer  transfer.Options
+
+	// websiteRedirects is the entries of --website-redirects.
+	websiteRedirects []redirect
+}
+
+// hasCondition reports whether the conditional write is specified.
+func (o *options) hasCondition() bool {
+	return o.transfer.NoOverwrite || o.transfer.IfMatch != ""
+}
+
+// hasObjectLock reports whether the Object Lock settings are specified.
+func (o *options) hasObjectLock() bool {
+	return o.transfer.ObjectLockMode != "" || o.transfer.ObjectLockLegalHoldStatus != ""
+}
+
+// parseOptions reads the flags initialized by Init, and validates them.
+func parseOptions(flags *pflag.FlagSet) (*options, error) {
+	r := &flagReader{flags: flags}
+	o := &options{
+		recursive: r.bool("recursive"),
+	}
+	t := &o.transfer
+	t.CopyThreshold = maxCopyObjectBytes
+	t.DryRun = r.bool("dryrun")
+	followSymlinks := r.bool("follow-symlinks") && !r.bool("no-follow-symlinks")
+	symlinks := r.string("symlinks")
+	t.AllowSymlinkEscape = r.bool("allow-symlink-escape")
+	t.Preserve = r.bool("preserve")
+	t.NoGuessMimeType = r.bool("no-guess-mime-type")
+	t.ContentType = r.string("content-type")
+	t.CacheControl = r.string("cache-control")
+	t.ContentDisposition = r.string("content-disposition")
+	t.ContentEncoding = r.string("content-encoding")
+	t.ContentLanguage = r.string("content-language")
+	aclName := r.string("acl")
+	expires := r.string("expires")
+	partSize := r.string("part-size")
+	partConcurrency := r.int("part-concurrency")
+	maxMemory := r.string("max-memory")
+	websiteRedirects := r.string("website-redirects")
+	objectLockMode := r.string("object-lock-mode")
+	objectLockRetainUntilDate := r.string("object-lock-retain-until-date")
+	objectLockLegalHoldStatus := r.string("object-lock-legal-hold-status")
+	t.NoOverwrite = r.bool("no-overwrite")
+	ifMatch := r.string("if-match")
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var err error
+	t.Concurrency, err = config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	t.MultipartThreshold, err = config.MultipartThreshold()
+	if err != nil {
+		return nil, err
+	}
+	t.MultipartChunkSize, err = config.MultipartChunkSize()
+	if err != nil {
+		return nil, err
+	}
+	if partSize != "" {
+		t.DownloadPartSize, err = config.ParseSize(partSize)
+		if err != nil || t.DownloadPartSize <= 0 {
+			return nil, fmt.Errorf("invalid part size: %s", partSize)
+		}
+	}
+	if partConcurrency < 0 {
+		return nil, fmt.Errorf("invalid part concurrency: %d", partConcurrency)
+	}
+	t.DownloadConcurrency = partConcurrency
+	if maxMemory != "" {
+		t.MaxMemory, err = config.ParseSize(maxMemory)
+		if err != nil || t.MaxMemory <= 0 {
+			return nil, fmt.Errorf("invalid max memory: %s", maxMemory)
+		}
+	}
+	t.ACL, err = acl.ParseCanned(aclName)
+	if err != nil {
+		return nil, err
+	}
+	if expires != "" {
+		e, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return nil, err
+		}
+		t.Expires = &e
+	}
+	t.ObjectLockMode, err = retention.ParseMode(objectLockMode)
+	if err != nil {
+		return nil, err
+	}
+	t.ObjectLockRetainUntilDate, err = retention.ParseRetainUntilDate(objectLockRetainUntilDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if (t.ObjectLockMode == "") != (t.ObjectLockRetainUntilDate == nil) {
+		return nil, fmt.Errorf("--object-lock-mode and --object-lock-retain-until-date must be specified together")
+	}
+	t.ObjectLockLegalHoldStatus, err = retention.ParseLegalHoldStatus(objectLockLegalHoldStatus)
+	if err != nil {
+		return nil, err
+	}
+	if ifMatch != "" {
+		if t.NoOverwrite {
+			return nil, fmt.Errorf("--no-overwrite and --if-match cannot be specified at the same time")
+		}
+		if !strings.HasPrefix(ifMatch, `"`) {
+			// S3 returns the ETags in double quotes, but they are often copied without quotes.
+			ifMatch = `"` + ifMatch + `"`
+		}
+		t.IfMatch = ifMatch
+	}
+	t.Symlinks, err = parseSymlinkMode(symlinks, followSymlinks)
+	if err != nil {
+		return nil, err
+	}
+	if t.AllowSymlinkEscape && t.Symlinks != transfer.SymlinkPreserve {
+		return nil, fmt.Errorf("--allow-symlink-escape requires --symlinks preserve")
+	}
+	if websiteRedirects != "" {
+		if !o.recursive {
+			return nil, fmt.Errorf("--website-redirects requires --recursive")
+		}
+		o.websiteRedirects, err = readRedirects(websiteRedirects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// parseSymlinkMode parses --symlinks.
+// If it is omitted, the mode comes from --follow-symlinks and --no-follow-symlinks.
+func parseSymlinkMode(mode string, follow bool) (transfer.SymlinkMode, error) {
+	var m transfer.SymlinkMode
+	switch mode {
+	case "":
+		if !follow {
+			return transfer.SymlinkSkip, nil
+		}
+		return transfer.SymlinkFollow, nil
+	case "skip":
+		return transfer.SymlinkSkip, nil
+	case "follow":
+		m = transfer.SymlinkFollow
+	case "preserve":
+		m = transfer.SymlinkPreserve
+	default:
+		return 0, fmt.Errorf("unknown symlinks mode: %s, it must be follow, skip or preserve", mode)
+	}
+	if !follow {
+		return 0, fmt.Errorf("--no-follow-symlinks conflicts with --symlinks %s", mode)
+	}
+	return m, nil
+}
+
+// flagReader reads the values of the flags, and keeps the first error.
+type flagReader struct {
+	flags *pflag.FlagSet
+	err   error
+}
+
+func (r *flagReader) bool(name string) bool {
+	v, err := r.flags.GetBool(name)
+	r.setError(err)
+	return v
+}
+
+func (r *flagReader) int(name string) int {
+	v, err := r.flags.GetInt(name)
+	r.setError(err)
+	return v
+}
+
+func (r *flagReader) string(name string) string {
+	v, err := r.flags.GetString(name)
+	r.setError(err)
+	return v
+}
+
+func (r *flagReader) setError(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
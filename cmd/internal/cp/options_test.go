@@ -0,0 +1,130 @@
+package cp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/transfer"
+	"github.com/spf13/cobra"
+)
+
+// newTestCommand returns a cp command with the flags parsed from args.
+func newTestCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	Init(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestParseOptions(t *testing.T) {
+	cases := []struct {
+		args []string
+		want func(o *options) bool
+	}{
+		{[]string{"--recursive"}, func(o *options) bool { return o.recursive }},
+		{[]string{"--dryrun"}, func(o *options) bool { return o.transfer.DryRun }},
+		{[]string{}, func(o *options) bool { return o.transfer.Symlinks == transfer.SymlinkFollow }},
+		{[]string{"--no-follow-symlinks"}, func(o *options) bool { return o.transfer.Symlinks == transfer.SymlinkSkip }},
+		{[]string{"--symlinks", "skip"}, func(o *options) bool { return o.transfer.Symlinks == transfer.SymlinkSkip }},
+		{[]string{"--symlinks", "preserve"}, func(o *options) bool { return o.transfer.Symlinks == transfer.SymlinkPreserve }},
+		{[]string{}, func(o *options) bool { return !o.transfer.AllowSymlinkEscape }},
+		{[]string{"--symlinks", "preserve", "--allow-symlink-escape"}, func(o *options) bool { return o.transfer.AllowSymlinkEscape }},
+		{[]string{"--no-follow-symlinks", "--symlinks", "skip"}, func(o *options) bool { return o.transfer.Symlinks == transfer.SymlinkSkip }},
+		{[]string{"--preserve"}, func(o *options) bool { return o.transfer.Preserve }},
+		{[]string{}, func(o *options) bool { return !o.transfer.Preserve }},
+		{[]string{"--no-guess-mime-type"}, func(o *options) bool { return o.transfer.NoGuessMimeType }},
+		{[]string{"--acl", "private"}, func(o *options) bool { return o.transfer.ACL == types.ObjectCannedACLPrivate }},
+		{[]string{"--content-type", "text/plain"}, func(o *options) bool { return o.transfer.ContentType == "text/plain" }},
+		{[]string{"--cache-control", "no-cache"}, func(o *options) bool {
+			return o.transfer.CacheControl == "no-cache" && o.transfer.ContentDisposition == ""
+		}},
+		{[]string{"--content-disposition", "attachment"}, func(o *options) bool {
+			return o.transfer.ContentDisposition == "attachment" && o.transfer.CacheControl == ""
+		}},
+		{[]string{"--content-encoding", "gzip"}, func(o *options) bool { return o.transfer.ContentEncoding == "gzip" }},
+		{[]string{"--content-language", "ja"}, func(o *options) bool { return o.transfer.ContentLanguage == "ja" }},
+		{[]string{"--expires", "2020-01-02T03:04:05Z"}, func(o *options) bool {
+			return o.transfer.Expires != nil && o.transfer.Expires.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		}},
+		{[]string{"--part-size", "16MB"}, func(o *options) bool { return o.transfer.DownloadPartSize == 16*1024*1024 }},
+		{[]string{"--part-concurrency", "10"}, func(o *options) bool { return o.transfer.DownloadConcurrency == 10 }},
+		{[]string{"--max-memory", "256MB"}, func(o *options) bool { return o.transfer.MaxMemory == 256*1024*1024 }},
+		{[]string{"--object-lock-mode", "GOVERNANCE", "--object-lock-retain-until-date", "2100-01-02T03:04:05Z"}, func(o *options) bool {
+			return o.transfer.ObjectLockMode == types.ObjectLockModeGovernance &&
+				o.transfer.ObjectLockRetainUntilDate.Equal(time.Date(2100, 1, 2, 3, 4, 5, 0, time.UTC)) &&
+				o.hasObjectLock()
+		}},
+		{[]string{"--object-lock-legal-hold-status", "ON"}, func(o *options) bool {
+			return o.transfer.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn && o.hasObjectLock()
+		}},
+		{[]string{}, func(o *options) bool { return !o.hasObjectLock() && !o.hasCondition() }},
+		{[]string{"--no-overwrite"}, func(o *options) bool { return o.transfer.NoOverwrite && o.hasCondition() }},
+		{[]string{"--if-match", "d41d8cd98f00b204e9800998ecf8427e"}, func(o *options) bool {
+			return o.transfer.IfMatch == `"d41d8cd98f00b204e9800998ecf8427e"` && o.hasCondition()
+		}},
+		{[]string{"--if-match", `"d41d8cd98f00b204e9800998ecf8427e"`}, func(o *options) bool {
+			return o.transfer.IfMatch == `"d41d8cd98f00b204e9800998ecf8427e"`
+		}},
+	}
+	for _, tc := range cases {
+		o, err := parseOptions(newTestCommand(t, tc.args...).Flags())
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.args, err)
+			continue
+		}
+		if !tc.want(o) {
+			t.Errorf("%v: unexpected options: %+v", tc.args, o)
+		}
+	}
+}
+
+func TestParseOptions_Invalid(t *testing.T) {
+	cases := [][]string{
+		{"--acl", "unknown"},
+		{"--expires", "tomorrow"},
+		{"--part-size", "0"},
+		{"--part-size", "foo"},
+		{"--part-concurrency", "-1"},
+		{"--max-memory", "foo"},
+		{"--website-redirects", "redirects.txt"},
+		{"--recursive", "--website-redirects", "testdata/no-such-file"},
+		{"--object-lock-mode", "GOVERNANCE"},
+		{"--object-lock-retain-until-date", "2100-01-02T03:04:05Z"},
+		{"--object-lock-mode", "LEGAL", "--object-lock-retain-until-date", "2100-01-02T03:04:05Z"},
+		{"--object-lock-mode", "COMPLIANCE", "--object-lock-retain-until-date", "2000-01-02T03:04:05Z"},
+		{"--object-lock-legal-hold-status", "MAYBE"},
+		{"--no-overwrite", "--if-match", "etag"},
+		{"--symlinks", "copy"},
+		{"--no-follow-symlinks", "--symlinks", "preserve"},
+		{"--allow-symlink-escape"},
+	}
+	for _, args := range cases {
+		if _, err := parseOptions(newTestCommand(t, args...).Flags()); err == nil {
+			t.Errorf("%v: want error, got nil", args)
+		}
+	}
+}
+
+func TestParseOptions_Reentrant(t *testing.T) {
+	cmd1 := newTestCommand(t, "--recursive", "--acl", "public-read")
+	cmd2 := newTestCommand(t)
+
+	o1, err := parseOptions(cmd1.Flags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o2, err := parseOptions(cmd2.Flags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o1.recursive || o1.transfer.ACL != types.ObjectCannedACLPublicRead {
+		t.Errorf("unexpected options: %+v", o1)
+	}
+	if o2.recursive || o2.transfer.ACL != "" {
+		t.Errorf("the options of another command leak: %+v", o2)
+	}
+}
@@ -0,0 +1,142 @@
+package cp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/shogo82148/s3cli-mini/transfer"
+)
+
+// redirect is an entry of the website redirects file.
+type redirect struct {
+	// path is the path of the redirected object, relative to the destination prefix.
+	path string
+
+	// location is the redirect location, an absolute path or a URL.
+	location string
+}
+
+// readRedirects reads the website redirects file.
+func readRedirects(name string) ([]redirect, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	redirects, err := parseRedirects(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return redirects, nil
+}
+
+// parseRedirects parses the website redirects.
+// Each line has the path and the location separated by white spaces, e.g.
+//
+//	/old/page.html  /new/page.html
+//	/blog/          https://blog.example.com/
+//
+// Empty lines and lines starting with # are ignored.
+func parseRedirects(r io.Reader) ([]redirect, error) {
+	var redirects []redirect
+	seen := map[string]int{}
+	s := bufio.NewScanner(r)
+	for num := 1; s.Scan(); num++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want a path and a location, got %q", num, line)
+		}
+		p := strings.TrimPrefix(fields[0], "/")
+		if p == "" {
+			return nil, fmt.Errorf("line %d: the root cannot be redirected", num)
+		}
+		location := fields[1]
+		if !strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+			return nil, fmt.Errorf("line %d: invalid location %q, it must start with /, http:// or https://", num, location)
+		}
+		if prev, ok := seen[p]; ok {
+			return nil, fmt.Errorf("line %d: duplicated path %q, it is already defined at line %d", num, fields[0], prev)
+		}
+		seen[p] = num
+		redirects = append(redirects, redirect{path: p, location: location})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return redirects, nil
+}
+
+// redirectKeys maps the keys under the prefix to the redirect locations.
+func redirectKeys(redirects []redirect, prefix string) map[string]string {
+	keys := make(map[string]string, len(redirects))
+	for _, r := range redirects {
+		keys[joinKey(prefix, r.path)] = r.location
+	}
+	return keys
+}
+
+// missingRedirects returns the redirects that have no local files in dir.
+// The empty objects are created for them.
+// The symbolic links are handled by mode in the same manner as transfer.Client.UploadDir,
+// so the files that UploadDir skips also get the empty objects.
+func missingRedirects(redirects []redirect, dir, prefix string, mode transfer.SymlinkMode) map[string]string {
+	keys := map[string]string{}
+	for _, r := range redirects {
+		if !strings.HasSuffix(r.path, "/") && isUploaded(dir, r.path, mode) {
+			continue
+		}
+		keys[joinKey(prefix, r.path)] = r.location
+	}
+	return keys
+}
+
+// isUploaded reports whether transfer.Client.UploadDir uploads the file at the slash-separated path p in dir.
+func isUploaded(dir, p string, mode transfer.SymlinkMode) bool {
+	if mode == transfer.SymlinkFollow {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p)))
+		return err == nil && !info.IsDir()
+	}
+
+	// UploadDir doesn't walk into the symbolic links to directories.
+	name := dir
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		name = filepath.Join(name, elem)
+		info, err := os.Lstat(name)
+		if err != nil {
+			return false
+		}
+		if i < len(elems)-1 {
+			if !info.IsDir() {
+				return false
+			}
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			// the link itself is uploaded with SymlinkPreserve.
+			return mode == transfer.SymlinkPreserve
+		}
+		return !info.IsDir()
+	}
+	return false
+}
+
+// joinKey joins the prefix and the path in the same manner as transfer.Client.UploadDir.
+// The trailing slash of p is kept, for the redirects of "directories".
+func joinKey(prefix, p string) string {
+	key := path.Join(prefix, p)
+	if strings.HasSuffix(p, "/") {
+		key += "/"
+	}
+	return key
+}
@@ -0,0 +1,134 @@
+package cp
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shogo82148/s3cli-mini/internal/testtree"
+	"github.com/shogo82148/s3cli-mini/transfer"
+)
+
+func TestParseRedirects(t *testing.T) {
+	input := `# moved pages
+/old.html   /new.html
+
+/blog/      https://blog.example.com/
+docs/v1/index.html	http://example.com/docs/
+`
+	got, err := parseRedirects(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []redirect{
+		{path: "old.html", location: "/new.html"},
+		{path: "blog/", location: "https://blog.example.com/"},
+		{path: "docs/v1/index.html", location: "http://example.com/docs/"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: want %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParseRedirects_Invalid(t *testing.T) {
+	tests := []string{
+		"/old.html",
+		"/old.html /new.html extra",
+		"/ /index.html",
+		"/old.html new.html",
+		"/old.html /a.html\nold.html /b.html",
+	}
+	for _, input := range tests {
+		if _, err := parseRedirects(strings.NewReader(input)); err == nil {
+			t.Errorf("%q: want error, got nil", input)
+		}
+	}
+}
+
+func TestMissingRedirects(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "old.html"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	redirects := []redirect{
+		{path: "old.html", location: "/new.html"},
+		{path: "gone.html", location: "/new.html"},
+		{path: "docs", location: "/docs/"},
+		{path: "blog/", location: "https://blog.example.com/"},
+	}
+
+	got := redirectKeys(redirects, "site")
+	want := map[string]string{
+		"site/old.html":  "/new.html",
+		"site/gone.html": "/new.html",
+		"site/docs":      "/docs/",
+		"site/blog/":     "https://blog.example.com/",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("redirectKeys: want %v, got %v", want, got)
+	}
+
+	got = missingRedirects(redirects, dir, "", transfer.SymlinkFollow)
+	want = map[string]string{
+		"gone.html": "/new.html",
+		"docs":      "/docs/",
+		"blog/":     "https://blog.example.com/",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("missingRedirects: want %v, got %v", want, got)
+	}
+}
+
+func TestMissingRedirects_Symlinks(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows", "plan9":
+		t.Skipf("skipping on %s", runtime.GOOS)
+	}
+	dir := t.TempDir()
+	testtree.Write(t, dir, map[string]string{
+		"real/index.html": "index",
+		"file.html":       "LINK:real/index.html",
+		"broken.html":     "LINK:no-such-file",
+		"linkdir":         "LINK:real",
+	})
+	redirects := []redirect{
+		{path: "file.html", location: "/new.html"},
+		{path: "broken.html", location: "/new.html"},
+		{path: "linkdir", location: "/real/"},
+		{path: "linkdir/index.html", location: "/real/index.html"},
+	}
+
+	cases := []struct {
+		mode transfer.SymlinkMode
+		want []string
+	}{
+		{transfer.SymlinkFollow, []string{"broken.html", "linkdir"}},
+		{transfer.SymlinkSkip, []string{"broken.html", "file.html", "linkdir", "linkdir/index.html"}},
+		{transfer.SymlinkPreserve, []string{"linkdir/index.html"}},
+	}
+	for _, tc := range cases {
+		got := missingRedirects(redirects, dir, "", tc.mode)
+		want := map[string]string{}
+		for _, key := range tc.want {
+			for _, r := range redirects {
+				if r.path == key {
+					want[key] = r.location
+				}
+			}
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("%s: want %v, got %v", tc.mode, want, got)
+		}
+	}
+}
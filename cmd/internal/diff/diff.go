@@ -0,0 +1,581 @@
+package diff
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/shogo82148/s3cli-mini/internal/fastwalk"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// the exit codes, same as diff(1).
+const (
+	exitDifferent = 1
+	exitTrouble   = 2
+)
+
+// the statuses of the results.
+const (
+	statusAdded   = "added"
+	statusRemoved = "removed"
+	statusChanged = "changed"
+)
+
+// the reasons of the changes.
+const (
+	reasonSize     = "size"
+	reasonMtime    = "mtime"
+	reasonETag     = "etag"
+	reasonChecksum = "checksum"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	cmd.Flags().Bool("json", false, "Displays the differences in JSON format.")
+}
+
+// objectAPI is the subset of S3 API that diff command uses.
+type objectAPI interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// location is a local directory or an S3 prefix.
+type location struct {
+	// dir is the local directory. It is empty if the location is in S3.
+	dir string
+
+	svc    objectAPI
+	bucket string
+	prefix string
+}
+
+func (l *location) isLocal() bool {
+	return l.dir != ""
+}
+
+// entry is a file or an object in a location.
+type entry struct {
+	loc   *location
+	path  string // the path relative to the location, separated by slashes.
+	size  int64
+	mtime time.Time
+
+	// the fields of S3 objects.
+	etag         string
+	checksumType types.ChecksumType
+	algorithms   []types.ChecksumAlgorithm
+	headOutput   *s3.HeadObjectOutput // cache of head
+}
+
+// head returns the metadata of the S3 object.
+func (e *entry) head(ctx context.Context) (*s3.HeadObjectOutput, error) {
+	if e.headOutput != nil {
+		return e.headOutput, nil
+	}
+	resp, err := e.loc.svc.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(e.loc.bucket),
+		Key:          aws.String(e.loc.prefix + e.path),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.headOutput = resp
+	return resp, nil
+}
+
+// result is a difference between the locations.
+type result struct {
+	Status string `json:"status"`
+	Path   string `json:"path"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// options is the options of diff command.
+type options struct {
+	json          bool
+	concurrency   int
+	multipartSize int64
+	chunkSize     int64
+}
+
+// Run runs diff command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 2 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(exitTrouble)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(exitTrouble)
+	}
+	src, err := newLocation(ctx, args[0])
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(exitTrouble)
+	}
+	dst, err := newLocation(ctx, args[1])
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(exitTrouble)
+	}
+	if src.isLocal() && dst.isLocal() {
+		cmd.PrintErrln("Validation error: at least one of the paths must be s3://bucket/prefix")
+		os.Exit(exitTrouble)
+	}
+
+	results, err := diff(ctx, src, dst, opts)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(exitTrouble)
+	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	err = printResults(w, results, opts)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(exitTrouble)
+	}
+	if len(results) > 0 {
+		os.Exit(exitDifferent)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	jsonMode, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := config.MultipartThreshold()
+	if err != nil {
+		return nil, err
+	}
+	chunkSize, err := config.MultipartChunkSize()
+	if err != nil {
+		return nil, err
+	}
+	return &options{
+		json:          jsonMode,
+		concurrency:   concurrency,
+		multipartSize: threshold,
+		chunkSize:     chunkSize,
+	}, nil
+}
+
+func newLocation(ctx context.Context, path string) (*location, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		return &location{dir: path}, nil
+	}
+	bucket, prefix := s3path.Parse(path)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return &location{svc: svc, bucket: bucket, prefix: prefix}, nil
+}
+
+// diff pairs up the entries in src and dst by their paths, and compares them.
+// The results are sorted by the paths.
+func diff(ctx context.Context, src, dst *location, opts *options) ([]result, error) {
+	var srcEntries, dstEntries map[string]*entry
+	lg, lctx := errgroup.WithContext(ctx)
+	lg.Go(func() error {
+		var err error
+		srcEntries, err = list(lctx, src)
+		return err
+	})
+	lg.Go(func() error {
+		var err error
+		dstEntries, err = list(lctx, dst)
+		return err
+	})
+	if err := lg.Wait(); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var results []result
+	add := func(r result) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, r)
+	}
+
+	// derive the group from the parent context,
+	// the context of the listing group is canceled by lg.Wait.
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(opts.concurrency, 1))
+	for path, s := range srcEntries {
+		d, ok := dstEntries[path]
+		if !ok {
+			add(result{Status: statusAdded, Path: path})
+			continue
+		}
+		g.Go(func() error {
+			reason, err := compare(ctx, s, d, opts)
+			if err != nil {
+				return fmt.Errorf("failed to compare %s: %w", path, err)
+			}
+			if reason != "" {
+				add(result{Status: statusChanged, Path: path, Reason: reason})
+			}
+			return nil
+		})
+	}
+	for path := range dstEntries {
+		if _, ok := srcEntries[path]; !ok {
+			add(result{Status: statusRemoved, Path: path})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	return results, nil
+}
+
+// list returns the entries in the location.
+func list(ctx context.Context, loc *location) (map[string]*entry, error) {
+	if loc.isLocal() {
+		return listLocal(loc)
+	}
+	return listS3(ctx, loc)
+}
+
+func listLocal(loc *location) (map[string]*entry, error) {
+	var mu sync.Mutex
+	entries := make(map[string]*entry)
+	err := fastwalk.Walk(loc.dir, func(path string, typ os.FileMode) error {
+		if typ.IsDir() {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(loc.dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		mu.Lock()
+		defer mu.Unlock()
+		entries[rel] = &entry{
+			loc:   loc,
+			path:  rel,
+			size:  info.Size(),
+			mtime: info.ModTime(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func listS3(ctx context.Context, loc *location) (map[string]*entry, error) {
+	entries := make(map[string]*entry)
+	p := s3.NewListObjectsV2Paginator(loc.svc, &s3.ListObjectsV2Input{
+		Bucket: aws.String(loc.bucket),
+		Prefix: aws.String(loc.prefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(aws.ToString(obj.Key), loc.prefix)
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				// skip the placeholder objects of the directories.
+				continue
+			}
+			entries[rel] = &entry{
+				loc:          loc,
+				path:         rel,
+				size:         aws.ToInt64(obj.Size),
+				mtime:        aws.ToTime(obj.LastModified),
+				etag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+				checksumType: obj.ChecksumType,
+				algorithms:   obj.ChecksumAlgorithm,
+			}
+		}
+	}
+	return entries, nil
+}
+
+// compare returns the reason why src and dst are different.
+// It returns an empty string if they are same.
+func compare(ctx context.Context, src, dst *entry, opts *options) (string, error) {
+	if src.size != dst.size {
+		return reasonSize, nil
+	}
+	switch {
+	case src.loc.isLocal():
+		return compareLocal(ctx, src, dst, opts)
+	case dst.loc.isLocal():
+		return compareLocal(ctx, dst, src, opts)
+	}
+	return compareS3(ctx, src, dst)
+}
+
+// compareLocal compares the local file with the S3 object.
+// It compares the ETag if it is the MD5 digest of the object, or the one of the multipart upload with the chunk size of cp.
+// Otherwise, it compares the checksum of the object if available, or the modification time at last.
+func compareLocal(ctx context.Context, local, remote *entry, opts *options) (string, error) {
+	name := filepath.Join(local.loc.dir, filepath.FromSlash(local.path))
+	if etag, ok, err := localETag(name, local.size, remote.etag, opts); err != nil {
+		return "", err
+	} else if ok {
+		if etag == remote.etag {
+			return "", nil
+		}
+
+		// the ETag of the object encrypted with SSE-KMS or SSE-C is not the MD5 digest.
+		// ListObjectsV2 doesn't return the encryption, so check it only if the ETags differ.
+		encrypted, err := isEncryptedETag(ctx, remote)
+		if err != nil {
+			return "", err
+		}
+		if !encrypted {
+			return reasonETag, nil
+		}
+	}
+
+	if algorithm, ok := fullObjectChecksum(remote); ok {
+		want, err := headChecksum(ctx, remote, algorithm)
+		if err != nil {
+			return "", err
+		}
+		if want != "" {
+			got, err := fileChecksum(name, algorithm)
+			if err != nil {
+				return "", err
+			}
+			if got != want {
+				return reasonChecksum, nil
+			}
+			return "", nil
+		}
+	}
+
+	// the object is older than the local file.
+	if local.mtime.After(remote.mtime) {
+		return reasonMtime, nil
+	}
+	return "", nil
+}
+
+// compareS3 compares the S3 objects.
+func compareS3(ctx context.Context, src, dst *entry) (string, error) {
+	if src.etag == dst.etag {
+		return "", nil
+	}
+
+	// the ETags differ if the objects are uploaded in different ways, even if the contents are same.
+	// compare the checksums if both objects have them.
+	srcAlgorithm, srcOK := fullObjectChecksum(src)
+	dstAlgorithm, dstOK := fullObjectChecksum(dst)
+	if !srcOK || !dstOK || srcAlgorithm != dstAlgorithm {
+		return reasonETag, nil
+	}
+	srcChecksum, err := headChecksum(ctx, src, srcAlgorithm)
+	if err != nil {
+		return "", err
+	}
+	dstChecksum, err := headChecksum(ctx, dst, dstAlgorithm)
+	if err != nil {
+		return "", err
+	}
+	if srcChecksum == "" || srcChecksum != dstChecksum {
+		return reasonChecksum, nil
+	}
+	return "", nil
+}
+
+// isEncryptedETag reports whether the object is encrypted in the way that its ETag is not the MD5 digest.
+func isEncryptedETag(ctx context.Context, e *entry) (bool, error) {
+	resp, err := e.head(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch resp.ServerSideEncryption {
+	case types.ServerSideEncryptionAwsKms, types.ServerSideEncryptionAwsKmsDsse:
+		return true, nil
+	}
+	return aws.ToString(resp.SSECustomerAlgorithm) != "", nil
+}
+
+// localETag computes the ETag of the local file in the same way as S3 does.
+// It returns false if the ETag can't be computed,
+// e.g. the object is uploaded in the multipart upload with unknown chunk size.
+func localETag(name string, size int64, etag string, opts *options) (string, bool, error) {
+	idx := strings.IndexByte(etag, '-')
+	if idx < 0 {
+		// the ETag of a single part upload is the MD5 digest of the object.
+		sum, err := fileHash(name, md5.New())
+		if err != nil {
+			return "", false, err
+		}
+		return hex.EncodeToString(sum), true, nil
+	}
+
+	// the ETag of a multipart upload is the MD5 digest of the MD5 digests of the parts, and the number of the parts.
+	parts, err := strconv.ParseInt(etag[idx+1:], 10, 64)
+	if err != nil || size <= opts.multipartSize || parts != (size+opts.chunkSize-1)/opts.chunkSize {
+		return "", false, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	digests := md5.New()
+	for range parts {
+		h := md5.New()
+		if _, err := io.CopyN(h, f, opts.chunkSize); err != nil && err != io.EOF {
+			return "", false, err
+		}
+		digests.Write(h.Sum(nil))
+	}
+	return fmt.Sprintf("%x-%d", digests.Sum(nil), parts), true, nil
+}
+
+// fullObjectChecksum returns the checksum algorithm of the whole object that can be computed locally.
+// The checksum covers the whole object if its type is FULL_OBJECT, or the object is uploaded in a single part.
+func fullObjectChecksum(e *entry) (types.ChecksumAlgorithm, bool) {
+	if e.checksumType != types.ChecksumTypeFullObject && strings.Contains(e.etag, "-") {
+		return "", false
+	}
+	for _, algorithm := range e.algorithms {
+		if newChecksum(algorithm) != nil {
+			return algorithm, true
+		}
+	}
+	return "", false
+}
+
+func newChecksum(algorithm types.ChecksumAlgorithm) hash.Hash {
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New()
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New()
+	}
+	return nil
+}
+
+// headChecksum returns the checksum of the object in base64.
+func headChecksum(ctx context.Context, e *entry, algorithm types.ChecksumAlgorithm) (string, error) {
+	resp, err := e.head(ctx)
+	if err != nil {
+		return "", err
+	}
+	var checksum *string
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		checksum = resp.ChecksumCRC32
+	case types.ChecksumAlgorithmCrc32c:
+		checksum = resp.ChecksumCRC32C
+	case types.ChecksumAlgorithmSha1:
+		checksum = resp.ChecksumSHA1
+	case types.ChecksumAlgorithmSha256:
+		checksum = resp.ChecksumSHA256
+	}
+	return aws.ToString(checksum), nil
+}
+
+// fileChecksum returns the checksum of the local file in base64.
+func fileChecksum(name string, algorithm types.ChecksumAlgorithm) (string, error) {
+	sum, err := fileHash(name, newChecksum(algorithm))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+func fileHash(name string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func printResults(w io.Writer, results []result, opts *options) error {
+	if opts.json {
+		if results == nil {
+			results = []result{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	for _, r := range results {
+		var err error
+		if r.Reason != "" {
+			_, err = fmt.Fprintf(w, "%s: %s (%s)\n", r.Status, r.Path, r.Reason)
+		} else {
+			_, err = fmt.Fprintf(w, "%s: %s\n", r.Status, r.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type fakeObject struct {
+	body      string
+	etag      string
+	modified  time.Time
+	algorithm types.ChecksumAlgorithm
+	checksum  string
+	sse       types.ServerSideEncryption
+	sseC      bool
+}
+
+// fakeObjectAPI serves the objects in memory.
+type fakeObjectAPI struct {
+	objects map[string]fakeObject
+}
+
+func (f *fakeObjectAPI) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for key, obj := range f.objects {
+		if !strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			continue
+		}
+		o := types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(int64(len(obj.body))),
+			ETag:         aws.String(`"` + obj.etag + `"`),
+			LastModified: aws.Time(obj.modified),
+		}
+		if obj.algorithm != "" {
+			o.ChecksumAlgorithm = []types.ChecksumAlgorithm{obj.algorithm}
+			o.ChecksumType = types.ChecksumTypeFullObject
+		}
+		out.Contents = append(out.Contents, o)
+	}
+	return out, nil
+}
+
+func (f *fakeObjectAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	obj := f.objects[aws.ToString(params.Key)]
+	out := &s3.HeadObjectOutput{ServerSideEncryption: obj.sse}
+	if obj.sseC {
+		out.SSECustomerAlgorithm = aws.String("AES256")
+	}
+	if obj.algorithm == types.ChecksumAlgorithmSha256 {
+		out.ChecksumSHA256 = aws.String(obj.checksum)
+	}
+	return out, nil
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Base64(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestDiff_LocalS3(t *testing.T) {
+	dir := t.TempDir()
+	past := time.Now().Add(-time.Hour)
+	files := map[string]string{
+		"same.txt":            "same",
+		"size.txt":            "local",
+		"etag.txt":            "aaaa",
+		"multipart.bin":       "0123456789",
+		"multipart-diff.bin":  "0123456789",
+		"checksum.txt":        "checksum",
+		"newer.txt":           "newer",
+		"added.txt":           "added",
+		"sub/same-nested.txt": "nested",
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(filepath.Join(dir, "newer.txt"), time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ETag of the multipart upload with 4 bytes chunks.
+	multipartETag := func(body string) string {
+		var digests []byte
+		for i := 0; i < len(body); i += 4 {
+			sum := md5.Sum([]byte(body[i:min(i+4, len(body))]))
+			digests = append(digests, sum[:]...)
+		}
+		return fmt.Sprintf("%s-%d", md5Hex(string(digests)), (len(body)+3)/4)
+	}
+
+	svc := &fakeObjectAPI{objects: map[string]fakeObject{
+		"prefix/same.txt":            {body: "same", etag: md5Hex("same"), modified: past},
+		"prefix/size.txt":            {body: "remote!", etag: md5Hex("remote!"), modified: past},
+		"prefix/etag.txt":            {body: "bbbb", etag: md5Hex("bbbb"), modified: past},
+		"prefix/multipart.bin":       {body: "0123456789", etag: multipartETag("0123456789"), modified: past},
+		"prefix/multipart-diff.bin":  {body: "9876543210", etag: multipartETag("9876543210"), modified: past},
+		"prefix/checksum.txt":        {body: "CHECKSUM", etag: "unknown-1", modified: past, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("CHECKSUM")},
+		"prefix/newer.txt":           {body: "older", etag: "unknown-1", modified: past},
+		"prefix/removed.txt":         {body: "removed", etag: md5Hex("removed"), modified: past},
+		"prefix/sub/same-nested.txt": {body: "nested", etag: md5Hex("nested"), modified: past},
+	}}
+
+	opts := &options{concurrency: 2, multipartSize: 4, chunkSize: 4}
+	local := &location{dir: dir}
+	remote := &location{svc: svc, bucket: "bucket", prefix: "prefix/"}
+	got, err := diff(t.Context(), local, remote, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []result{
+		{Status: statusAdded, Path: "added.txt"},
+		{Status: statusChanged, Path: "checksum.txt", Reason: reasonChecksum},
+		{Status: statusChanged, Path: "etag.txt", Reason: reasonETag},
+		{Status: statusChanged, Path: "multipart-diff.bin", Reason: reasonETag},
+		{Status: statusChanged, Path: "newer.txt", Reason: reasonMtime},
+		{Status: statusRemoved, Path: "removed.txt"},
+		{Status: statusChanged, Path: "size.txt", Reason: reasonSize},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	// reverse the direction.
+	got, err = diff(t.Context(), remote, local, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[0].Status != statusRemoved || got[5].Status != statusAdded {
+		t.Errorf("unexpected results: %+v", got)
+	}
+}
+
+func TestDiff_Encrypted(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sse-s3.txt":   "same",
+		"kms.txt":      "same",
+		"kms-diff.txt": "local",
+		"sse-c.txt":    "same",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the ETags of the objects encrypted with SSE-KMS or SSE-C are not the MD5 digests.
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	svc := &fakeObjectAPI{objects: map[string]fakeObject{
+		"sse-s3.txt":   {body: "diff", etag: md5Hex("diff"), modified: future, sse: types.ServerSideEncryptionAes256},
+		"kms.txt":      {body: "same", etag: md5Hex("random"), modified: future, sse: types.ServerSideEncryptionAwsKms},
+		"kms-diff.txt": {body: "REMOT", etag: md5Hex("random"), modified: past, sse: types.ServerSideEncryptionAwsKms},
+		"sse-c.txt":    {body: "same", etag: md5Hex("random"), modified: past, sseC: true, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("same")},
+	}}
+
+	opts := &options{concurrency: 2, multipartSize: 4, chunkSize: 4}
+	got, err := diff(t.Context(), &location{dir: dir}, &location{svc: svc, bucket: "bucket"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []result{
+		{Status: statusChanged, Path: "kms-diff.txt", Reason: reasonMtime},
+		{Status: statusChanged, Path: "sse-s3.txt", Reason: reasonETag},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestDiff_S3S3(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	src := &fakeObjectAPI{objects: map[string]fakeObject{
+		"a/same.txt":     {body: "same", etag: md5Hex("same"), modified: past},
+		"a/copied.txt":   {body: "copied", etag: "multipart-2", modified: past, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("copied")},
+		"a/changed.txt":  {body: "aaaa", etag: md5Hex("aaaa"), modified: past},
+		"a/checksum.txt": {body: "aaaa", etag: "multipart-2", modified: past, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("aaaa")},
+	}}
+	dst := &fakeObjectAPI{objects: map[string]fakeObject{
+		"b/same.txt":     {body: "same", etag: md5Hex("same"), modified: time.Now()},
+		"b/copied.txt":   {body: "copied", etag: md5Hex("copied"), modified: past, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("copied")},
+		"b/changed.txt":  {body: "bbbb", etag: md5Hex("bbbb"), modified: past},
+		"b/checksum.txt": {body: "bbbb", etag: md5Hex("bbbb"), modified: past, algorithm: types.ChecksumAlgorithmSha256, checksum: sha256Base64("bbbb")},
+	}}
+
+	opts := &options{concurrency: 2, multipartSize: 4, chunkSize: 4}
+	got, err := diff(t.Context(),
+		&location{svc: src, bucket: "bucket", prefix: "a/"},
+		&location{svc: dst, bucket: "bucket", prefix: "b/"},
+		opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []result{
+		{Status: statusChanged, Path: "changed.txt", Reason: reasonETag},
+		{Status: statusChanged, Path: "checksum.txt", Reason: reasonChecksum},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
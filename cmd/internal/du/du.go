@@ -0,0 +1,287 @@
+package du
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/ls"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.Int("max-depth", -1, "Displays the usage of the prefixes only if they are N or fewer levels below the specified prefix. -1 means no limit.")
+	flags.Bool("human-readable", false, "Displays sizes in human readable format.")
+	flags.Bool("json", false, "Displays the usage in JSON format.")
+}
+
+// options is the options of du command.
+type options struct {
+	maxDepth      int
+	humanReadable bool
+	json          bool
+	concurrency   int
+}
+
+// Run runs du command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, prefix := s3path.Parse(args[0])
+	if bucket == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/prefix")
+		os.Exit(1)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	root, err := walk(ctx, svc, bucket, prefix, opts)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := printUsage(cmd.OutOrStdout(), bucket, root, opts); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	maxDepth, err := flags.GetInt("max-depth")
+	if err != nil {
+		return nil, err
+	}
+	if maxDepth < -1 {
+		return nil, fmt.Errorf("invalid max depth: %d", maxDepth)
+	}
+	humanReadable, err := flags.GetBool("human-readable")
+	if err != nil {
+		return nil, err
+	}
+	jsonMode, err := flags.GetBool("json")
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	return &options{
+		maxDepth:      maxDepth,
+		humanReadable: humanReadable,
+		json:          jsonMode,
+		concurrency:   concurrency,
+	}, nil
+}
+
+// usage is the total size and the number of objects.
+type usage struct {
+	Size    int64 `json:"size"`
+	Objects int64 `json:"objects"`
+}
+
+func (u *usage) add(v usage) {
+	u.Size += v.Size
+	u.Objects += v.Objects
+}
+
+// node is the usage of a prefix.
+type node struct {
+	prefix   string
+	depth    int
+	total    usage
+	classes  map[types.ObjectStorageClass]*usage
+	children []*node
+}
+
+func newNode(prefix string, depth int) *node {
+	return &node{
+		prefix:  prefix,
+		depth:   depth,
+		classes: make(map[types.ObjectStorageClass]*usage),
+	}
+}
+
+func (n *node) addObject(obj types.Object) {
+	class := obj.StorageClass
+	if class == "" {
+		class = types.ObjectStorageClassStandard
+	}
+	v := usage{Size: aws.ToInt64(obj.Size), Objects: 1}
+	n.total.add(v)
+	u, ok := n.classes[class]
+	if !ok {
+		u = &usage{}
+		n.classes[class] = u
+	}
+	u.add(v)
+}
+
+// sum adds the usage of the children to n.
+func (n *node) sum() {
+	sort.Slice(n.children, func(i, j int) bool {
+		return n.children[i].prefix < n.children[j].prefix
+	})
+	for _, child := range n.children {
+		child.sum()
+		n.total.add(child.total)
+		for class, v := range child.classes {
+			u, ok := n.classes[class]
+			if !ok {
+				u = &usage{}
+				n.classes[class] = u
+			}
+			u.add(*v)
+		}
+	}
+}
+
+// walk lists the objects under the prefix, and returns their usage.
+// The common prefixes are listed in parallel until opts.maxDepth,
+// and the deeper prefixes are listed without the delimiter.
+func walk(ctx context.Context, svc interfaces.ObjectListerV2, bucket, prefix string, opts *options) (*node, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(opts.concurrency, 1))
+
+	var walkPrefix func(n *node) error
+	walkPrefix = func(n *node) error {
+		input := &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(n.prefix),
+		}
+		flat := opts.maxDepth >= 0 && n.depth >= opts.maxDepth
+		if !flat {
+			input.Delimiter = aws.String("/")
+		}
+		p := s3.NewListObjectsV2Paginator(svc, input)
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, obj := range page.Contents {
+				n.addObject(obj)
+			}
+			for _, prefix := range page.CommonPrefixes {
+				child := newNode(aws.ToString(prefix.Prefix), n.depth+1)
+				n.children = append(n.children, child)
+
+				// walk the child in the current goroutine if all workers are busy,
+				// so the workers never wait for each other.
+				if !g.TryGo(func() error { return walkPrefix(child) }) {
+					if err := walkPrefix(child); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	root := newNode(prefix, 0)
+	g.Go(func() error { return walkPrefix(root) })
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	root.sum()
+	return root, nil
+}
+
+// entry is the JSON representation of node.
+type entry struct {
+	Path           string           `json:"path"`
+	Size           int64            `json:"size"`
+	Objects        int64            `json:"objects"`
+	StorageClasses map[string]usage `json:"storage_classes"`
+}
+
+// printUsage writes the usage of the prefixes in the post-order as du(1) does,
+// so the total of the specified prefix comes last.
+func printUsage(w io.Writer, bucket string, root *node, opts *options) error {
+	var entries []entry
+	var visit func(n *node)
+	visit = func(n *node) {
+		if opts.maxDepth < 0 || n.depth < opts.maxDepth {
+			for _, child := range n.children {
+				visit(child)
+			}
+		}
+		classes := make(map[string]usage, len(n.classes))
+		for class, u := range n.classes {
+			classes[string(class)] = *u
+		}
+		entries = append(entries, entry{
+			Path:           "s3://" + bucket + "/" + n.prefix,
+			Size:           n.total.Size,
+			Objects:        n.total.Objects,
+			StorageClasses: classes,
+		})
+	}
+	visit(root)
+
+	if opts.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	for _, e := range entries {
+		classes := make([]string, 0, len(e.StorageClasses))
+		for class := range e.StorageClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		breakdown := make([]string, 0, len(classes))
+		for _, class := range classes {
+			u := e.StorageClasses[class]
+			breakdown = append(breakdown, fmt.Sprintf("%s: %s/%d", class, formatSize(u.Size, opts), u.Objects))
+		}
+		line := fmt.Sprintf("%10s %10d %s", formatSize(e.Size, opts), e.Objects, e.Path)
+		if len(breakdown) > 0 {
+			line += " (" + strings.Join(breakdown, ", ") + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatSize(size int64, opts *options) string {
+	if opts.humanReadable {
+		return ls.MakeHumanReadable(size)
+	}
+	return fmt.Sprint(size)
+}
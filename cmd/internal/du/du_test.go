@@ -0,0 +1,119 @@
+package du
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+)
+
+// newFakeLister returns a bucket with the objects, that lists two keys per page.
+func newFakeLister(sizes map[string]int64, classes map[string]types.ObjectStorageClass) *testutils.FakeS3 {
+	f := testutils.NewFakeS3()
+	f.PageSize = 2
+	for key, size := range sizes {
+		f.AddObject(types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(size),
+			StorageClass: classes[key],
+		})
+	}
+	return f
+}
+
+func TestWalk(t *testing.T) {
+	svc := newFakeLister(map[string]int64{
+		"a.txt":         1,
+		"foo/a.txt":     10,
+		"foo/b.txt":     20,
+		"foo/bar/a.txt": 100,
+		"foo/bar/b.txt": 200,
+		"foo/baz/a.txt": 1000,
+		"qux/a.txt":     10000,
+	}, map[string]types.ObjectStorageClass{
+		"foo/baz/a.txt": types.ObjectStorageClassGlacier,
+	})
+
+	cases := []struct {
+		maxDepth int
+		want     []entry
+	}{
+		{
+			maxDepth: 0,
+			want: []entry{
+				{Path: "s3://bucket/", Size: 11331, Objects: 7},
+			},
+		},
+		{
+			maxDepth: 1,
+			want: []entry{
+				{Path: "s3://bucket/foo/", Size: 1330, Objects: 5},
+				{Path: "s3://bucket/qux/", Size: 10000, Objects: 1},
+				{Path: "s3://bucket/", Size: 11331, Objects: 7},
+			},
+		},
+		{
+			maxDepth: -1,
+			want: []entry{
+				{Path: "s3://bucket/foo/bar/", Size: 300, Objects: 2},
+				{Path: "s3://bucket/foo/baz/", Size: 1000, Objects: 1},
+				{Path: "s3://bucket/foo/", Size: 1330, Objects: 5},
+				{Path: "s3://bucket/qux/", Size: 10000, Objects: 1},
+				{Path: "s3://bucket/", Size: 11331, Objects: 7},
+			},
+		},
+	}
+	for _, tc := range cases {
+		opts := &options{maxDepth: tc.maxDepth, json: true, concurrency: 2}
+		root, err := walk(t.Context(), svc, "bucket", "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := printUsage(&buf, "bucket", root, opts); err != nil {
+			t.Fatal(err)
+		}
+		var got []entry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("max-depth %d: want %d entries, got %d: %s", tc.maxDepth, len(tc.want), len(got), buf.String())
+			continue
+		}
+		for i := range got {
+			if got[i].Path != tc.want[i].Path || got[i].Size != tc.want[i].Size || got[i].Objects != tc.want[i].Objects {
+				t.Errorf("max-depth %d: want %+v, got %+v", tc.maxDepth, tc.want[i], got[i])
+			}
+		}
+
+		total := got[len(got)-1].StorageClasses
+		if total["GLACIER"] != (usage{Size: 1000, Objects: 1}) {
+			t.Errorf("max-depth %d: unexpected GLACIER usage: %+v", tc.maxDepth, total["GLACIER"])
+		}
+		if total["STANDARD"] != (usage{Size: 10331, Objects: 6}) {
+			t.Errorf("max-depth %d: unexpected STANDARD usage: %+v", tc.maxDepth, total["STANDARD"])
+		}
+	}
+}
+
+func TestPrintUsage_HumanReadable(t *testing.T) {
+	svc := newFakeLister(map[string]int64{"foo/a": 1536}, nil)
+	opts := &options{maxDepth: -1, humanReadable: true, concurrency: 1}
+	root, err := walk(t.Context(), svc, "bucket", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printUsage(&buf, "bucket", root, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "   1.5 KiB          1 s3://bucket/foo/ (STANDARD: 1.5 KiB/1)\n" +
+		"   1.5 KiB          1 s3://bucket/ (STANDARD: 1.5 KiB/1)\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
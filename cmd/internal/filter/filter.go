@@ -0,0 +1,126 @@
+// Package filter implements --include and --exclude filters of the AWS CLI.
+// See https://docs.aws.amazon.com/cli/latest/reference/s3/#use-of-exclude-and-include-filters
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Filter filters the paths by the patterns.
+// The patterns are applied in the order they are specified, and the last matching pattern wins.
+// The paths that match no pattern are included.
+type Filter struct {
+	rules []rule
+}
+
+type rule struct {
+	include bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// AddFlags adds --include and --exclude flags to flags.
+func AddFlags(flags *pflag.FlagSet) {
+	rules := &rulesValue{}
+	flags.Var(&ruleValue{rules: rules, include: true}, "include", "Don't exclude files or objects in the command that match the specified pattern. See Use of Exclude and Include Filters for details.")
+	flags.Var(&ruleValue{rules: rules, include: false}, "exclude", "Exclude all files or objects from the command that matches the specified pattern.")
+}
+
+// FromFlags returns the filter of the flags added by AddFlags.
+func FromFlags(flags *pflag.FlagSet) (*Filter, error) {
+	f := flags.Lookup("include")
+	if f == nil {
+		return nil, fmt.Errorf("flag accessed but not defined: include")
+	}
+	v, ok := f.Value.(*ruleValue)
+	if !ok {
+		return nil, fmt.Errorf("flag include is not added by filter.AddFlags")
+	}
+	return &Filter{rules: v.rules.rules}, nil
+}
+
+// Match reports whether the path is included.
+// The path is relative to the source directory or prefix, separated by slashes.
+func (f *Filter) Match(path string) bool {
+	ok := true
+	for _, r := range f.rules {
+		if r.re.MatchString(path) {
+			ok = r.include
+		}
+	}
+	return ok
+}
+
+// compile converts the pattern of the AWS CLI to a regular expression.
+// "*" matches everything including slashes, "?" matches any single character,
+// and "[seq]" matches any character in seq.
+func compile(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			j := strings.IndexByte(pattern[i+1:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("invalid pattern: %q", pattern)
+			}
+			class := pattern[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += j + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// rulesValue is the rules shared by --include and --exclude, to keep their order.
+type rulesValue struct {
+	rules []rule
+}
+
+// ruleValue is pflag.Value of --include or --exclude.
+type ruleValue struct {
+	rules   *rulesValue
+	include bool
+}
+
+func (v *ruleValue) String() string {
+	var patterns []string
+	for _, r := range v.rules.rules {
+		if r.include == v.include {
+			patterns = append(patterns, r.pattern)
+		}
+	}
+	return "[" + strings.Join(patterns, ",") + "]"
+}
+
+func (v *ruleValue) Set(pattern string) error {
+	re, err := compile(pattern)
+	if err != nil {
+		return err
+	}
+	v.rules.rules = append(v.rules.rules, rule{
+		include: v.include,
+		pattern: pattern,
+		re:      re,
+	})
+	return nil
+}
+
+// Type returns "stringArray", so the config file can set the patterns as a list.
+func (v *ruleValue) Type() string {
+	return "stringArray"
+}
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFilter(t *testing.T) {
+	cases := []struct {
+		args []string
+		path string
+		want bool
+	}{
+		{nil, "foo.txt", true},
+		{[]string{"--exclude", "*"}, "foo.txt", false},
+		{[]string{"--exclude", "*.txt"}, "dir/foo.txt", false},
+		{[]string{"--exclude", "*.txt"}, "foo.jpg", true},
+		{[]string{"--exclude", "*", "--include", "*.txt"}, "foo.txt", true},
+		{[]string{"--exclude", "*", "--include", "*.txt"}, "foo.jpg", false},
+		{[]string{"--include", "*.txt", "--exclude", "*"}, "foo.txt", false},
+		{[]string{"--exclude", "dir/*"}, "dir/sub/foo.txt", false},
+		{[]string{"--exclude", "dir/*"}, "other/dir/foo.txt", true},
+		{[]string{"--exclude", "foo?.txt"}, "foo1.txt", false},
+		{[]string{"--exclude", "foo?.txt"}, "foo12.txt", true},
+		{[]string{"--exclude", "foo[0-9].txt"}, "foo1.txt", false},
+		{[]string{"--exclude", "foo[!0-9].txt"}, "foo1.txt", true},
+		{[]string{"--exclude", "a+b(c).txt"}, "a+b(c).txt", false},
+	}
+	for _, tc := range cases {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		AddFlags(flags)
+		if err := flags.Parse(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		f, err := FromFlags(flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Match(tc.path); got != tc.want {
+			t.Errorf("%v %s: want %t, got %t", tc.args, tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestFilter_InvalidPattern(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddFlags(flags)
+	if err := flags.Parse([]string{"--exclude", "[a"}); err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -0,0 +1,389 @@
+package find
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+)
+
+// the maximum number of keys in a DeleteObjects request.
+const deleteBatchSize = 1000
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.String("name", "", "Base of the key (the path with the leading prefixes removed) matches the shell pattern, e.g. '*.log'.")
+	flags.String("size", "", "The size of the object is greater than (+N), less than (-N) or exactly (N) N bytes, e.g. +1G.")
+	flags.String("mtime", "", "The object was last modified less than (-N) or more than (+N) N ago, e.g. -7d. The units are s, m, h, d and w. The default unit is d.")
+	flags.String("newer-than", "", "The object was last modified after the time, e.g. 2006-01-02 or 2006-01-02T15:04:05Z.")
+	flags.StringArray("storage-class", []string{}, "The storage class of the object is the value. It can be specified multiple times.")
+	flags.StringArray("tag", []string{}, "The object has the tag KEY=VALUE, or the tag KEY with any value. It can be specified multiple times.")
+	flags.Bool("exec-delete", false, "Deletes the found objects.")
+	flags.Bool("print0", false, "Separates the found objects by the null character instead of the newline.")
+}
+
+// findAPI is the subset of S3 API that find command uses.
+type findAPI interface {
+	interfaces.ObjectListerV2
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+}
+
+// predicate reports whether the object matches the condition.
+// It uses only the fields in the listing, so it doesn't send any request.
+type predicate func(obj types.Object) bool
+
+// tagCondition is the condition of --tag. If value is nil, any value matches.
+type tagCondition struct {
+	key   string
+	value *string
+}
+
+// options is the options of find command.
+type options struct {
+	predicates []predicate
+	tags       []tagCondition
+	execDelete bool
+	print0     bool
+}
+
+// Run runs find command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd, time.Now())
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, prefix := s3path.Parse(args[0])
+	if bucket == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/prefix")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	err = find(ctx, svc, w, bucket, prefix, opts)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command, now time.Time) (*options, error) {
+	flags := cmd.Flags()
+	name, err := flags.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	size, err := flags.GetString("size")
+	if err != nil {
+		return nil, err
+	}
+	mtime, err := flags.GetString("mtime")
+	if err != nil {
+		return nil, err
+	}
+	newerThan, err := flags.GetString("newer-than")
+	if err != nil {
+		return nil, err
+	}
+	storageClasses, err := flags.GetStringArray("storage-class")
+	if err != nil {
+		return nil, err
+	}
+	tags, err := flags.GetStringArray("tag")
+	if err != nil {
+		return nil, err
+	}
+	execDelete, err := flags.GetBool("exec-delete")
+	if err != nil {
+		return nil, err
+	}
+	print0, err := flags.GetBool("print0")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &options{
+		execDelete: execDelete,
+		print0:     print0,
+	}
+	if name != "" {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern: %q", name)
+		}
+		opts.predicates = append(opts.predicates, func(obj types.Object) bool {
+			key := aws.ToString(obj.Key)
+			ok, _ := path.Match(name, key[strings.LastIndexByte(key, '/')+1:])
+			return ok
+		})
+	}
+	if size != "" {
+		p, err := parseSize(size)
+		if err != nil {
+			return nil, err
+		}
+		opts.predicates = append(opts.predicates, p)
+	}
+	if mtime != "" {
+		p, err := parseMtime(mtime, now)
+		if err != nil {
+			return nil, err
+		}
+		opts.predicates = append(opts.predicates, p)
+	}
+	if newerThan != "" {
+		t, err := parseTime(newerThan)
+		if err != nil {
+			return nil, err
+		}
+		opts.predicates = append(opts.predicates, func(obj types.Object) bool {
+			return aws.ToTime(obj.LastModified).After(t)
+		})
+	}
+	if len(storageClasses) > 0 {
+		classes := make(map[types.ObjectStorageClass]bool, len(storageClasses))
+		for _, class := range storageClasses {
+			classes[types.ObjectStorageClass(strings.ToUpper(class))] = true
+		}
+		opts.predicates = append(opts.predicates, func(obj types.Object) bool {
+			class := obj.StorageClass
+			if class == "" {
+				class = types.ObjectStorageClassStandard
+			}
+			return classes[class]
+		})
+	}
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid tag: %q", tag)
+		}
+		cond := tagCondition{key: key}
+		if ok {
+			cond.value = aws.String(value)
+		}
+		opts.tags = append(opts.tags, cond)
+	}
+	return opts, nil
+}
+
+// parseSize parses the condition of --size, e.g. +1G, -100K or 0.
+func parseSize(s string) (predicate, error) {
+	sign, n := cutSign(s)
+	size, err := config.ParseSize(n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size: %q", s)
+	}
+	switch sign {
+	case '+':
+		return func(obj types.Object) bool { return aws.ToInt64(obj.Size) > size }, nil
+	case '-':
+		return func(obj types.Object) bool { return aws.ToInt64(obj.Size) < size }, nil
+	}
+	return func(obj types.Object) bool { return aws.ToInt64(obj.Size) == size }, nil
+}
+
+// parseMtime parses the condition of --mtime, e.g. -7d or +12h.
+func parseMtime(s string, now time.Time) (predicate, error) {
+	sign, n := cutSign(s)
+	if sign == 0 {
+		return nil, fmt.Errorf("invalid mtime: %q, it must start with + or -", s)
+	}
+	d, err := parseDuration(n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mtime: %q", s)
+	}
+	t := now.Add(-d)
+	if sign == '+' {
+		return func(obj types.Object) bool { return aws.ToTime(obj.LastModified).Before(t) }, nil
+	}
+	return func(obj types.Object) bool { return aws.ToTime(obj.LastModified).After(t) }, nil
+}
+
+func cutSign(s string) (byte, string) {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		return s[0], s[1:]
+	}
+	return 0, s
+}
+
+// parseDuration parses the duration with the units s, m, h, d and w. The default unit is d.
+func parseDuration(s string) (time.Duration, error) {
+	unit := 24 * time.Hour
+	if s != "" && (s[len(s)-1] < '0' || s[len(s)-1] > '9') {
+		switch s[len(s)-1] {
+		case 's':
+			unit = time.Second
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		default:
+			return 0, errors.New("unknown unit")
+		}
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %q", s)
+	}
+	return t, nil
+}
+
+// find streams the listing of the objects under the prefix, and writes the objects that match the options to w.
+// It keeps only one page of the listing and one batch of the deletion in memory.
+func find(ctx context.Context, svc findAPI, w io.Writer, bucket, prefix string, opts *options) error {
+	sep := "\n"
+	if opts.print0 {
+		sep = "\x00"
+	}
+	d := &deleter{svc: svc, bucket: bucket}
+
+	p := s3.NewListObjectsV2Paginator(svc, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			ok, err := match(ctx, svc, bucket, obj, opts)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if _, err := io.WriteString(w, "s3://"+bucket+"/"+aws.ToString(obj.Key)+sep); err != nil {
+				return err
+			}
+			if opts.execDelete {
+				if err := d.add(ctx, aws.ToString(obj.Key)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return d.flush(ctx)
+}
+
+// match reports whether the object matches the options.
+// The tags are checked at last, because it needs a request per object.
+func match(ctx context.Context, svc findAPI, bucket string, obj types.Object, opts *options) (bool, error) {
+	for _, p := range opts.predicates {
+		if !p(obj) {
+			return false, nil
+		}
+	}
+	if len(opts.tags) == 0 {
+		return true, nil
+	}
+
+	resp, err := svc.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    obj.Key,
+	})
+	if err != nil {
+		return false, err
+	}
+	tags := make(map[string]string, len(resp.TagSet))
+	for _, tag := range resp.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for _, cond := range opts.tags {
+		v, ok := tags[cond.key]
+		if !ok || cond.value != nil && v != *cond.value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deleter deletes the objects by DeleteObjects in batches.
+type deleter struct {
+	svc    findAPI
+	bucket string
+	keys   []types.ObjectIdentifier
+}
+
+func (d *deleter) add(ctx context.Context, key string) error {
+	d.keys = append(d.keys, types.ObjectIdentifier{Key: aws.String(key)})
+	if len(d.keys) < deleteBatchSize {
+		return nil
+	}
+	return d.flush(ctx)
+}
+
+func (d *deleter) flush(ctx context.Context) error {
+	if len(d.keys) == 0 {
+		return nil
+	}
+	resp, err := d.svc.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(d.bucket),
+		Delete: &types.Delete{
+			Objects: d.keys,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	d.keys = d.keys[:0]
+	if err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		var errs []error
+		for _, e := range resp.Errors {
+			errs = append(errs, fmt.Errorf("failed to delete s3://%s/%s: %s", d.bucket, aws.ToString(e.Key), aws.ToString(e.Message)))
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package find
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+)
+
+var now = time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+// newFakeFindAPI returns a bucket with the test objects, that lists two keys per page.
+func newFakeFindAPI() *testutils.FakeS3 {
+	object := func(key string, size int64, age time.Duration, class types.ObjectStorageClass) types.Object {
+		return types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(size),
+			LastModified: aws.Time(now.Add(-age)),
+			StorageClass: class,
+		}
+	}
+	f := testutils.NewFakeS3()
+	f.PageSize = 2
+	f.AddObject(object("a.log", 100, time.Hour, ""))
+	f.AddObject(object("b.txt", 2<<30, 30*24*time.Hour, types.ObjectStorageClassStandard))
+	f.AddObject(object("logs/c.log", 1<<20, 3*24*time.Hour, types.ObjectStorageClassGlacier))
+	f.AddObject(object("logs/d.log", 0, 10*24*time.Hour, types.ObjectStorageClassStandardIa))
+	f.AddObject(object("logs/e.txt", 5<<30, time.Minute, types.ObjectStorageClassGlacier))
+	f.Tags["a.log"] = map[string]string{"env": "prod"}
+	f.Tags["logs/c.log"] = map[string]string{"env": "dev"}
+	return f
+}
+
+func runFind(t *testing.T, svc findAPI, prefix string, args ...string) string {
+	t.Helper()
+	opts, err := parseOptions(testutils.NewCommand(t, Init, args...), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := find(t.Context(), svc, &buf, "bucket", prefix, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFind(t *testing.T) {
+	cases := []struct {
+		args   []string
+		prefix string
+		want   []string
+	}{
+		{nil, "", []string{"a.log", "b.txt", "logs/c.log", "logs/d.log", "logs/e.txt"}},
+		{nil, "logs/", []string{"logs/c.log", "logs/d.log", "logs/e.txt"}},
+		{[]string{"--name", "*.log"}, "", []string{"a.log", "logs/c.log", "logs/d.log"}},
+		{[]string{"--size", "+1G"}, "", []string{"b.txt", "logs/e.txt"}},
+		{[]string{"--size", "-1M"}, "", []string{"a.log", "logs/d.log"}},
+		{[]string{"--size", "0"}, "", []string{"logs/d.log"}},
+		{[]string{"--mtime", "-7d"}, "", []string{"a.log", "logs/c.log", "logs/e.txt"}},
+		{[]string{"--mtime", "+1w"}, "", []string{"b.txt", "logs/d.log"}},
+		{[]string{"--mtime", "-2h"}, "", []string{"a.log", "logs/e.txt"}},
+		{[]string{"--newer-than", "2024-01-05T00:00:00Z"}, "", []string{"a.log", "logs/c.log", "logs/e.txt"}},
+		{[]string{"--storage-class", "STANDARD"}, "", []string{"a.log", "b.txt"}},
+		{[]string{"--storage-class", "glacier", "--storage-class", "STANDARD_IA"}, "", []string{"logs/c.log", "logs/d.log", "logs/e.txt"}},
+		{[]string{"--tag", "env"}, "", []string{"a.log", "logs/c.log"}},
+		{[]string{"--tag", "env=prod"}, "", []string{"a.log"}},
+		{[]string{"--name", "*.log", "--storage-class", "GLACIER"}, "", []string{"logs/c.log"}},
+	}
+	for _, tc := range cases {
+		got := runFind(t, newFakeFindAPI(), tc.prefix, tc.args...)
+		var want string
+		for _, key := range tc.want {
+			want += "s3://bucket/" + key + "\n"
+		}
+		if got != want {
+			t.Errorf("%v: want %q, got %q", tc.args, want, got)
+		}
+	}
+}
+
+func TestFind_Print0(t *testing.T) {
+	got := runFind(t, newFakeFindAPI(), "logs/", "--print0", "--name", "*.log")
+	want := "s3://bucket/logs/c.log\x00s3://bucket/logs/d.log\x00"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestFind_ExecDelete(t *testing.T) {
+	svc := newFakeFindAPI()
+	for i := range 2500 {
+		svc.AddObject(types.Object{
+			Key:  aws.String("tmp/" + strconv.Itoa(i)),
+			Size: aws.Int64(1),
+		})
+	}
+	runFind(t, svc, "tmp/", "--exec-delete")
+	if len(svc.Deleted) != 2500 {
+		t.Errorf("want 2500 objects deleted, got %d", len(svc.Deleted))
+	}
+	if svc.DeleteBatches != 3 {
+		t.Errorf("want 3 batches, got %d", svc.DeleteBatches)
+	}
+}
+
+func TestParseOptions_Invalid(t *testing.T) {
+	cases := [][]string{
+		{"--name", "[a"},
+		{"--size", "+foo"},
+		{"--mtime", "7d"},
+		{"--mtime", "-7x"},
+		{"--newer-than", "yesterday"},
+		{"--tag", "=foo"},
+	}
+	for _, args := range cases {
+		if _, err := parseOptions(testutils.NewCommand(t, Init, args...), now); err == nil {
+			t.Errorf("%v: want error, got nil", args)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package head
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+)
+
+// the size of the first ranged GET.
+// The following GETs double the size up to maxChunkSize, until enough lines are read.
+const (
+	minChunkSize = 64 * 1024
+	maxChunkSize = 8 * 1024 * 1024
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	cmd.Flags().IntP("lines", "n", 10, "The number of lines to print.")
+}
+
+// objectGetter is the subset of S3 API that head command uses.
+type objectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Run runs head command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	lines, err := cmd.Flags().GetInt("lines")
+	if err == nil && lines < 0 {
+		err = fmt.Errorf("invalid number of lines: %d", lines)
+	}
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || key == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	if err := head(ctx, svc, w, bucket, key, lines); err != nil {
+		w.Flush()
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := w.Flush(); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+// head writes the first n lines of the object to w.
+// It reads the object by small ranged GETs, so it doesn't download the whole object.
+func head(ctx context.Context, svc objectGetter, w io.Writer, bucket, key string, n int) error {
+	var pos int64
+	var etag *string
+	chunkSize := int64(minChunkSize)
+	for n > 0 {
+		resp, err := svc.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", pos, pos+chunkSize-1)),
+
+			// make sure that all chunks are read from the same object.
+			IfMatch: etag,
+		})
+		if err != nil {
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange" {
+				// the object is empty.
+				return nil
+			}
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		etag = resp.ETag
+		read := int64(len(data))
+		pos += read
+
+		for n > 0 {
+			idx := bytes.IndexByte(data, '\n')
+			if idx < 0 {
+				break
+			}
+			if _, err := w.Write(data[:idx+1]); err != nil {
+				return err
+			}
+			data = data[idx+1:]
+			n--
+		}
+		if n == 0 {
+			break
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+
+		size, ok := parseContentRangeSize(aws.ToString(resp.ContentRange))
+		if !ok || pos >= size || read == 0 {
+			// reached the end of the object.
+			// Content-Range is missing if the whole object is returned.
+			break
+		}
+		chunkSize = min(chunkSize*2, maxChunkSize)
+	}
+	return nil
+}
+
+// parseContentRangeSize returns the complete length in the Content-Range header,
+// e.g. 1234 for "bytes 0-99/1234".
+func parseContentRangeSize(contentRange string) (int64, bool) {
+	idx := strings.LastIndexByte(contentRange, '/')
+	if idx < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
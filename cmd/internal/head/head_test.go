@@ -0,0 +1,89 @@
+package head
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+type fakeObjectGetter struct {
+	data []byte
+
+	requests int
+	bytes    int64
+}
+
+func (f *fakeObjectGetter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.requests++
+	if f.requests > 1 && aws.ToString(params.IfMatch) != `"etag"` {
+		return nil, fmt.Errorf("want If-Match, got %q", aws.ToString(params.IfMatch))
+	}
+	var start, end int
+	if _, err := fmt.Sscanf(aws.ToString(params.Range), "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+	if start >= len(f.data) {
+		return nil, &smithy.GenericAPIError{Code: "InvalidRange"}
+	}
+	end = min(end, len(f.data)-1)
+	f.bytes += int64(end - start + 1)
+	return &s3.GetObjectOutput{
+		Body:         io.NopCloser(bytes.NewReader(f.data[start : end+1])),
+		ContentRange: aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, len(f.data))),
+		ETag:         aws.String(`"etag"`),
+	}, nil
+}
+
+func TestHead(t *testing.T) {
+	var lines []string
+	for i := range 100000 {
+		lines = append(lines, fmt.Sprintf("line %d\n", i))
+	}
+	svc := &fakeObjectGetter{data: []byte(strings.Join(lines, ""))}
+
+	var buf bytes.Buffer
+	if err := head(t.Context(), svc, &buf, "bucket", "key", 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "line 0\nline 1\nline 2\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if svc.bytes > minChunkSize {
+		t.Errorf("want at most %d bytes are read, got %d", minChunkSize, svc.bytes)
+	}
+}
+
+func TestHead_LongLines(t *testing.T) {
+	data := strings.Repeat("a", 3*minChunkSize) + "\n" + "b\n" + "c\n"
+	svc := &fakeObjectGetter{data: []byte(data)}
+
+	var buf bytes.Buffer
+	if err := head(t.Context(), svc, &buf, "bucket", "key", 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), data[:len(data)-2]; got != want {
+		t.Errorf("unexpected output: %d bytes", len(got))
+	}
+}
+
+func TestHead_ShortObject(t *testing.T) {
+	cases := []string{"", "a", "a\nb", "a\nb\n"}
+	for _, data := range cases {
+		svc := &fakeObjectGetter{data: []byte(data)}
+		var buf bytes.Buffer
+		if err := head(t.Context(), svc, &buf, "bucket", "key", 10); err != nil {
+			t.Errorf("%q: unexpected error: %v", data, err)
+			continue
+		}
+		if buf.String() != data {
+			t.Errorf("want %q, got %q", data, buf.String())
+		}
+	}
+}
@@ -1,12 +0,0 @@
-package interfaces
-
-import (
-	"context"
-
-	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
-)
-
-// DownloaderClient foobar
-type DownloaderClient interface {
-	DownloadObject(ctx context.Context, input *transfermanager.DownloadObjectInput, optFns ...func(*transfermanager.Options)) (*transfermanager.DownloadObjectOutput, error)
-}
@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
 	"github.com/spf13/cobra"
 )
 
@@ -59,7 +60,7 @@ func listBuckets(ctx context.Context, cmd *cobra.Command) {
 }
 
 func listObjects(ctx context.Context, cmd *cobra.Command, path string) {
-	bucket, key := parsePath(path)
+	bucket, key := s3path.Parse(path)
 	svc, err := config.NewS3BucketClient(ctx, bucket)
 	if err != nil {
 		cmd.PrintErrln(err)
@@ -84,54 +85,49 @@ func listObjects(ctx context.Context, cmd *cobra.Command, path string) {
 			os.Exit(1)
 		}
 		objects += int64(len(page.Contents))
-		// merge Contents and CommonPrefixes
-		contents := page.Contents
-		prefixes := page.CommonPrefixes
-		for len(contents) > 0 && len(prefixes) > 0 {
-			if aws.ToString(contents[0].Key) < aws.ToString(prefixes[0].Prefix) {
-				printObject(cmd, contents[0])
-				totalBytes += aws.ToInt64(contents[0].Size)
-				contents = contents[1:]
-			} else {
-				printPrefix(cmd, prefixes[0])
-				prefixes = prefixes[1:]
-			}
-		}
-		for _, obj := range contents {
+		Merge(page.Contents, page.CommonPrefixes, func(obj types.Object) {
 			printObject(cmd, obj)
 			totalBytes += aws.ToInt64(obj.Size)
-		}
-		for _, prefix := range prefixes {
+		}, func(prefix types.CommonPrefix) {
 			printPrefix(cmd, prefix)
-		}
+		})
 	}
 
 	if summarize {
 		cmd.Printf("\nTotal Objects: %d\n", objects)
 		if humanReadable {
-			cmd.Printf("   Total Size: %s\n", makeHumanReadable(totalBytes))
+			cmd.Printf("   Total Size: %s\n", MakeHumanReadable(totalBytes))
 		} else {
 			cmd.Printf("   Total Size: %d\n", totalBytes)
 		}
 	}
 }
 
-func parsePath(path string) (bucket, key string) {
-	path = strings.TrimPrefix(path, "s3://")
-	if idx := strings.IndexByte(path, '/'); idx > 0 {
-		bucket = path[:idx]
-		key = path[idx+1:]
-		return
+// Merge merges Contents and CommonPrefixes of a ListObjectsV2 page in the key order,
+// and calls object for each object and prefix for each common prefix.
+func Merge(contents []types.Object, prefixes []types.CommonPrefix, object func(types.Object), prefix func(types.CommonPrefix)) {
+	for len(contents) > 0 && len(prefixes) > 0 {
+		if aws.ToString(contents[0].Key) < aws.ToString(prefixes[0].Prefix) {
+			object(contents[0])
+			contents = contents[1:]
+		} else {
+			prefix(prefixes[0])
+			prefixes = prefixes[1:]
+		}
+	}
+	for _, obj := range contents {
+		object(obj)
+	}
+	for _, p := range prefixes {
+		prefix(p)
 	}
-	bucket = path
-	return
 }
 
 func printObject(cmd *cobra.Command, obj types.Object) {
 	date := aws.ToTime(obj.LastModified).In(time.Local).Format("2006-01-02 15:04:05")
 	size := obj.Size
 	if humanReadable {
-		cmd.Printf("%s %10s %s\n", date, makeHumanReadable(aws.ToInt64(size)), aws.ToString(obj.Key))
+		cmd.Printf("%s %10s %s\n", date, MakeHumanReadable(aws.ToInt64(size)), aws.ToString(obj.Key))
 	} else {
 		cmd.Printf("%s %10d %s\n", date, aws.ToInt64(size), aws.ToString(obj.Key))
 	}
@@ -141,8 +137,9 @@ func printPrefix(cmd *cobra.Command, prefix types.CommonPrefix) {
 	cmd.Printf("                           PRE %s\n", aws.ToString(prefix.Prefix))
 }
 
+// MakeHumanReadable formats size in the human readable format, e.g. "1.5 KiB".
 // port of https://github.com/aws/aws-cli/blob/072688cc07578144060aead8b75556fd986e0f2f/awscli/customizations/s3/utils.py#L47-L77
-func makeHumanReadable(size int64) string {
+func MakeHumanReadable(size int64) string {
 	if size == 1 {
 		return "1 Byte"
 	}
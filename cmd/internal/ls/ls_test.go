@@ -155,7 +155,7 @@ func TestMakeHumanReadable(t *testing.T) {
 		{1 << 60, "1.0 EiB"},
 	}
 	for _, tt := range cases {
-		got := makeHumanReadable(tt.in)
+		got := MakeHumanReadable(tt.in)
 		if got != tt.out {
 			t.Errorf("%d byte(s): want %s, got %s", tt.in, tt.out, got)
 		}
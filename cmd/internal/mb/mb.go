@@ -2,23 +2,60 @@ package mb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
 	"github.com/spf13/cobra"
 )
 
 var bucketNamespace string
+var objectLockEnabled bool
+var objectOwnership string
+var blockPublicAccess bool
+var versioning bool
+var defaultEncryption string
+var tags map[string]string
 
 // Init initializes mb command.
 func Init(cmd *cobra.Command) {
 	flags := cmd.Flags()
 	flags.StringVar(&bucketNamespace, "bucket-namespace", "global", "Specify the namespace for the bucket")
+	flags.BoolVar(&objectLockEnabled, "object-lock-enabled", false, "Enable S3 Object Lock for the bucket. Versioning is enabled automatically.")
+	flags.StringVar(&objectOwnership, "object-ownership", "", "The object ownership setting of the bucket. BucketOwnerEnforced, BucketOwnerPreferred or ObjectWriter.")
+	flags.BoolVar(&blockPublicAccess, "block-public-access", false, "Block all public access to the bucket and its objects.")
+	flags.BoolVar(&versioning, "versioning", false, "Enable versioning for the bucket.")
+	flags.StringVar(&defaultEncryption, "default-encryption", "", "The default server-side encryption of the bucket. AES256, aws:kms or aws:kms:<key-id>.")
+	flags.StringToStringVar(&tags, "tags", nil, "The tags of the bucket. e.g. --tags Key1=Value1,Key2=Value2")
+}
+
+// bucketAPI is the subset of S3 API that mb command uses.
+type bucketAPI interface {
+	interfaces.BucketCreator
+	interfaces.BucketDeleter
+	PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error)
+	PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+	PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error)
+	PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error)
+}
+
+// bucketOptions is the configuration of a new bucket.
+type bucketOptions struct {
+	namespace         types.BucketNamespace
+	region            string
+	objectLockEnabled bool
+	objectOwnership   types.ObjectOwnership
+	blockPublicAccess bool
+	versioning        bool
+	encryption        *types.ServerSideEncryptionByDefault
+	tags              []types.Tag
 }
 
 // Run runs mb command.
@@ -34,42 +71,199 @@ func Run(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	var namespace types.BucketNamespace
-	switch bucketNamespace {
-	case "global":
-		namespace = types.BucketNamespaceGlobal
-	case "account-regional":
-		namespace = types.BucketNamespaceAccountRegional
-	default:
-		log.Fatalf("invalid bucket namespace: %s, valid values are 'global' or 'account-regional'", bucketNamespace)
+	var opts bucketOptions
+	var err error
+	opts.namespace, err = parseBucketNamespace(bucketNamespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts.objectOwnership, err = parseObjectOwnership(objectOwnership)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts.encryption, err = parseEncryption(defaultEncryption)
+	if err != nil {
+		log.Fatal(err)
 	}
+	opts.objectLockEnabled = objectLockEnabled
+	opts.blockPublicAccess = blockPublicAccess
+	opts.versioning = versioning
+	opts.tags = parseTags(tags)
 
 	bucketName := strings.TrimPrefix(args[0], "s3://")
 
+	// the region is resolved from the --region flag, the environment values, or the profile.
 	cfg, err := config.LoadAWSConfig(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-	region := cfg.Region
+	opts.region = cfg.Region
 
 	svc, err := config.NewS3Client(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if err := makeBucket(ctx, svc, bucketName, &opts); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("make_bucket: s3://%s\n", bucketName)
+}
+
+// makeBucket creates the bucket and applies the configurations.
+// If any configuration fails, the bucket is deleted.
+func makeBucket(ctx context.Context, svc bucketAPI, bucketName string, opts *bucketOptions) error {
 	input := &s3.CreateBucketInput{
 		Bucket:          aws.String(bucketName),
-		BucketNamespace: namespace,
+		BucketNamespace: opts.namespace,
+		ObjectOwnership: opts.objectOwnership,
+	}
+	if opts.objectLockEnabled {
+		input.ObjectLockEnabledForBucket = aws.Bool(true)
 	}
-	if region != "us-east-1" {
+	if opts.region != "" && opts.region != "us-east-1" {
 		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
-			LocationConstraint: types.BucketLocationConstraint(region),
+			LocationConstraint: types.BucketLocationConstraint(opts.region),
 		}
 	}
-	_, err = svc.CreateBucket(ctx, input)
-	if err != nil {
-		log.Fatal(err)
+	if _, err := svc.CreateBucket(ctx, input); err != nil {
+		return err
 	}
 
-	fmt.Printf("make_bucket: s3://%s\n", bucketName)
+	if err := configureBucket(ctx, svc, bucketName, opts); err != nil {
+		// roll back
+		_, rollbackErr := svc.DeleteBucket(context.WithoutCancel(ctx), &s3.DeleteBucketInput{
+			Bucket: aws.String(bucketName),
+		})
+		if rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to delete the bucket s3://%s: %w", bucketName, rollbackErr))
+		}
+		return err
+	}
+	return nil
+}
+
+func configureBucket(ctx context.Context, svc bucketAPI, bucketName string, opts *bucketOptions) error {
+	if opts.blockPublicAccess {
+		_, err := svc.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+			Bucket: aws.String(bucketName),
+			PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(true),
+				IgnorePublicAcls:      aws.Bool(true),
+				RestrictPublicBuckets: aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to block public access: %w", err)
+		}
+	}
+
+	if opts.versioning {
+		_, err := svc.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+			Bucket: aws.String(bucketName),
+			VersioningConfiguration: &types.VersioningConfiguration{
+				Status: types.BucketVersioningStatusEnabled,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable versioning: %w", err)
+		}
+	}
+
+	if opts.encryption != nil {
+		_, err := svc.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+			Bucket: aws.String(bucketName),
+			ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+				Rules: []types.ServerSideEncryptionRule{
+					{
+						ApplyServerSideEncryptionByDefault: opts.encryption,
+						BucketKeyEnabled:                   aws.Bool(opts.encryption.SSEAlgorithm == types.ServerSideEncryptionAwsKms),
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure default encryption: %w", err)
+		}
+	}
+
+	if len(opts.tags) > 0 {
+		_, err := svc.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+			Bucket: aws.String(bucketName),
+			Tagging: &types.Tagging{
+				TagSet: opts.tags,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put tags: %w", err)
+		}
+	}
+	return nil
+}
+
+func parseBucketNamespace(namespace string) (types.BucketNamespace, error) {
+	switch namespace {
+	case "global":
+		return types.BucketNamespaceGlobal, nil
+	case "account-regional":
+		return types.BucketNamespaceAccountRegional, nil
+	}
+	return "", fmt.Errorf("invalid bucket namespace: %s, valid values are 'global' or 'account-regional'", namespace)
+}
+
+func parseObjectOwnership(ownership string) (types.ObjectOwnership, error) {
+	switch ownership {
+	case "":
+		return "", nil
+	case "BucketOwnerEnforced":
+		return types.ObjectOwnershipBucketOwnerEnforced, nil
+	case "BucketOwnerPreferred":
+		return types.ObjectOwnershipBucketOwnerPreferred, nil
+	case "ObjectWriter":
+		return types.ObjectOwnershipObjectWriter, nil
+	}
+	return "", fmt.Errorf("invalid object ownership: %s, valid values are 'BucketOwnerEnforced', 'BucketOwnerPreferred' or 'ObjectWriter'", ownership)
+}
+
+func parseEncryption(encryption string) (*types.ServerSideEncryptionByDefault, error) {
+	switch encryption {
+	case "":
+		return nil, nil
+	case "AES256":
+		return &types.ServerSideEncryptionByDefault{
+			SSEAlgorithm: types.ServerSideEncryptionAes256,
+		}, nil
+	case "aws:kms":
+		return &types.ServerSideEncryptionByDefault{
+			SSEAlgorithm: types.ServerSideEncryptionAwsKms,
+		}, nil
+	}
+	if keyID, ok := strings.CutPrefix(encryption, "aws:kms:"); ok && keyID != "" {
+		return &types.ServerSideEncryptionByDefault{
+			SSEAlgorithm:   types.ServerSideEncryptionAwsKms,
+			KMSMasterKeyID: aws.String(keyID),
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid default encryption: %s, valid values are 'AES256', 'aws:kms' or 'aws:kms:<key-id>'", encryption)
+}
+
+func parseTags(tags map[string]string) []types.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		ret = append(ret, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(tags[k]),
+		})
+	}
+	return ret
 }
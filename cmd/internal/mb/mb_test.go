@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
@@ -108,3 +110,90 @@ func TestMB_AccountRegional(t *testing.T) {
 		t.Fatalf("bucket %s is not found: %s", bucketName, err)
 	}
 }
+
+func TestParseEncryption(t *testing.T) {
+	cases := []struct {
+		in        string
+		algorithm types.ServerSideEncryption
+		keyID     string
+	}{
+		{"AES256", types.ServerSideEncryptionAes256, ""},
+		{"aws:kms", types.ServerSideEncryptionAwsKms, ""},
+		{"aws:kms:alias/foo", types.ServerSideEncryptionAwsKms, "alias/foo"},
+		{
+			"aws:kms:arn:aws:kms:us-east-2:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			types.ServerSideEncryptionAwsKms,
+			"arn:aws:kms:us-east-2:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		},
+	}
+	for _, tt := range cases {
+		got, err := parseEncryption(tt.in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got.SSEAlgorithm != tt.algorithm {
+			t.Errorf("%s: want %s, got %s", tt.in, tt.algorithm, got.SSEAlgorithm)
+		}
+		if aws.ToString(got.KMSMasterKeyID) != tt.keyID {
+			t.Errorf("%s: want %s, got %s", tt.in, tt.keyID, aws.ToString(got.KMSMasterKeyID))
+		}
+	}
+
+	for _, in := range []string{"aws:kms:", "aes256", "foobar"} {
+		if _, err := parseEncryption(in); err == nil {
+			t.Errorf("%s: want error, got nil", in)
+		}
+	}
+}
+
+type fakeBucketAPI struct {
+	bucketAPI
+	created bool
+	deleted bool
+	input   *s3.CreateBucketInput
+}
+
+func (f *fakeBucketAPI) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	f.created = true
+	f.input = params
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (f *fakeBucketAPI) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	f.deleted = true
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func (f *fakeBucketAPI) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
+
+func (f *fakeBucketAPI) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	return nil, errors.New("access denied")
+}
+
+func TestMakeBucket_Rollback(t *testing.T) {
+	svc := &fakeBucketAPI{}
+	err := makeBucket(t.Context(), svc, "example-bucket", &bucketOptions{
+		region:            "ap-northeast-1",
+		objectOwnership:   types.ObjectOwnershipBucketOwnerEnforced,
+		blockPublicAccess: true,
+		versioning:        true,
+	})
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if !svc.created {
+		t.Error("the bucket is not created")
+	}
+	if !svc.deleted {
+		t.Error("the bucket is not rolled back")
+	}
+	if got := svc.input.CreateBucketConfiguration.LocationConstraint; got != "ap-northeast-1" {
+		t.Errorf("unexpected location constraint: want %s, got %s", "ap-northeast-1", got)
+	}
+	if got := svc.input.ObjectOwnership; got != types.ObjectOwnershipBucketOwnerEnforced {
+		t.Errorf("unexpected object ownership: want %s, got %s", types.ObjectOwnershipBucketOwnerEnforced, got)
+	}
+}
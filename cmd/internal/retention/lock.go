@@ -0,0 +1,54 @@
+package retention
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ParseMode parses the Object Lock retention mode.
+// It returns an empty mode for an empty string.
+func ParseMode(mode string) (types.ObjectLockMode, error) {
+	switch strings.ToUpper(mode) {
+	case "":
+		return "", nil
+	case "GOVERNANCE":
+		return types.ObjectLockModeGovernance, nil
+	case "COMPLIANCE":
+		return types.ObjectLockModeCompliance, nil
+	}
+	return "", fmt.Errorf("unknown object lock mode: %s, it must be GOVERNANCE or COMPLIANCE", mode)
+}
+
+// ParseLegalHoldStatus parses the Object Lock legal hold status.
+// It returns an empty status for an empty string.
+func ParseLegalHoldStatus(status string) (types.ObjectLockLegalHoldStatus, error) {
+	switch strings.ToUpper(status) {
+	case "":
+		return "", nil
+	case "ON":
+		return types.ObjectLockLegalHoldStatusOn, nil
+	case "OFF":
+		return types.ObjectLockLegalHoldStatusOff, nil
+	}
+	return "", fmt.Errorf("unknown legal hold status: %s, it must be ON or OFF", status)
+}
+
+// ParseRetainUntilDate parses the date in RFC 3339 format, e.g. 2030-01-02T03:04:05Z.
+// The date must be in the future.
+// It returns nil for an empty string.
+func ParseRetainUntilDate(date string, now time.Time) (*time.Time, error) {
+	if date == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retain until date: %s, it must be in RFC 3339 format", date)
+	}
+	if !t.After(now) {
+		return nil, fmt.Errorf("invalid retain until date: %s, it must be in the future", date)
+	}
+	return &t, nil
+}
@@ -0,0 +1,395 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// errCodeNoLock is the error code of GetObjectRetention and GetObjectLegalHold
+// for the objects without the retention or the legal hold.
+const errCodeNoLock = "NoSuchObjectLockConfiguration"
+
+// objectAPI is the subset of the S3 API that retention command uses.
+type objectAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObjectLegalHold(ctx context.Context, params *s3.GetObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.GetObjectLegalHoldOutput, error)
+	GetObjectRetention(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error)
+	PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error)
+	PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+}
+
+// InitGet initializes flags of retention get command.
+func InitGet(cmd *cobra.Command) {
+	initCommon(cmd.Flags())
+}
+
+// InitExtend initializes flags of retention extend command.
+func InitExtend(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	initCommon(flags)
+	flags.String("retain-until-date", "", "The date until which the objects are retained, in RFC 3339 format. e.g. 2030-01-02T03:04:05Z")
+	flags.String("mode", "", "The retention mode, GOVERNANCE or COMPLIANCE. The current mode is kept if it is omitted.")
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+}
+
+// InitLegalHold initializes flags of retention legal-hold command.
+func InitLegalHold(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	initCommon(flags)
+	flags.String("status", "", "The legal hold status, ON or OFF.")
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+}
+
+func initCommon(flags *pflag.FlagSet) {
+	flags.Bool("recursive", false, "Command is performed on all objects under the specified prefix.")
+	filter.AddFlags(flags)
+}
+
+// options is the options of retention commands.
+type options struct {
+	batch batch.Options
+
+	// mode and retainUntil are the retention to extend to.
+	mode        types.ObjectLockRetentionMode
+	retainUntil *time.Time
+
+	// legalHold is the legal hold status to set.
+	legalHold types.ObjectLockLegalHoldStatus
+
+	dryrun bool
+}
+
+// RunGet runs retention get command.
+func RunGet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.get(ctx)
+	})
+}
+
+// RunExtend runs retention extend command.
+func RunExtend(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.extend(ctx)
+	})
+}
+
+// RunLegalHold runs retention legal-hold command.
+func RunLegalHold(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.setLegalHold(ctx)
+	})
+}
+
+func run(cmd *cobra.Command, args []string, fn func(ctx context.Context, c *client) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd, time.Now())
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || (key == "" && !opts.batch.Recursive) {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	c := &client{
+		svc:    svc,
+		w:      cmd.OutOrStdout(),
+		bucket: bucket,
+		key:    key,
+		opts:   opts,
+	}
+	if err := fn(ctx, c); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command, now time.Time) (*options, error) {
+	flags := cmd.Flags()
+	recursive, err := flags.GetBool("recursive")
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter.FromFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	opts := &options{
+		batch: batch.Options{
+			Recursive:   recursive,
+			Filter:      f,
+			Concurrency: concurrency,
+		},
+	}
+
+	if flags.Lookup("retain-until-date") != nil {
+		date, err := flags.GetString("retain-until-date")
+		if err != nil {
+			return nil, err
+		}
+		if date == "" {
+			return nil, errors.New("--retain-until-date is required")
+		}
+		opts.retainUntil, err = ParseRetainUntilDate(date, now)
+		if err != nil {
+			return nil, err
+		}
+
+		mode, err := flags.GetString("mode")
+		if err != nil {
+			return nil, err
+		}
+		m, err := ParseMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		opts.mode = types.ObjectLockRetentionMode(m)
+	}
+
+	if flags.Lookup("status") != nil {
+		status, err := flags.GetString("status")
+		if err != nil {
+			return nil, err
+		}
+		if status == "" {
+			return nil, errors.New("--status is required")
+		}
+		opts.legalHold, err = ParseLegalHoldStatus(status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if flags.Lookup("dryrun") != nil {
+		dryrun, err := flags.GetBool("dryrun")
+		if err != nil {
+			return nil, err
+		}
+		opts.dryrun = dryrun
+	}
+	return opts, nil
+}
+
+type client struct {
+	svc    objectAPI
+	bucket string
+	key    string
+	opts   *options
+
+	// mu protects w from the concurrent writes.
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// get prints the retention and the legal hold of the object.
+// In the recursive mode, it prints them of each object in a line.
+func (c *client) get(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		retention, err := c.getRetention(ctx, key)
+		if err != nil {
+			return err
+		}
+		legalHold, err := c.getLegalHold(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.opts.batch.Recursive {
+			tw := tabwriter.NewWriter(c.w, 0, 8, 1, ' ', 0)
+			fmt.Fprintf(tw, "Mode:\t%s\n", orNone(string(retention.Mode)))
+			fmt.Fprintf(tw, "Retain Until Date:\t%s\n", orNone(formatTime(retention.RetainUntilDate)))
+			fmt.Fprintf(tw, "Legal Hold:\t%s\n", orNone(string(legalHold)))
+			return tw.Flush()
+		}
+		_, err = fmt.Fprintf(c.w, "s3://%s/%s\t%s\t%s\t%s\n", c.bucket, key,
+			orNone(string(retention.Mode)), orNone(formatTime(retention.RetainUntilDate)), orNone(string(legalHold)))
+		return err
+	})
+}
+
+// extend extends the retention of the objects to opts.retainUntil.
+// The retention is never shortened, the objects already retained longer are skipped.
+func (c *client) extend(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		current, err := c.getRetention(ctx, key)
+		if err != nil {
+			return err
+		}
+		next, ok, err := extendRetention(current, c.opts.mode, *c.opts.retainUntil)
+		if err != nil {
+			return fmt.Errorf("s3://%s/%s: %w", c.bucket, key, err)
+		}
+		if !ok {
+			return c.printf("skip s3://%s/%s: %s until %s\n", c.bucket, key, current.Mode, formatTime(current.RetainUntilDate))
+		}
+		return c.putRetention(ctx, key, next)
+	})
+}
+
+// extendRetention returns the retention that current is extended to.
+// It returns false if current already satisfies the retention.
+func extendRetention(current *types.ObjectLockRetention, mode types.ObjectLockRetentionMode, until time.Time) (*types.ObjectLockRetention, bool, error) {
+	if current.Mode == "" || current.RetainUntilDate == nil {
+		if mode == "" {
+			return nil, false, errors.New("the object has no retention, --mode is required")
+		}
+		return &types.ObjectLockRetention{Mode: mode, RetainUntilDate: &until}, true, nil
+	}
+
+	if mode == "" {
+		mode = current.Mode
+	}
+	if current.Mode == types.ObjectLockRetentionModeCompliance && mode != types.ObjectLockRetentionModeCompliance {
+		return nil, false, errors.New("the retention in COMPLIANCE mode cannot be changed to GOVERNANCE mode")
+	}
+	if mode == current.Mode && !until.After(*current.RetainUntilDate) {
+		return nil, false, nil
+	}
+
+	// changing the mode must not shorten the retention.
+	if current.RetainUntilDate.After(until) {
+		until = *current.RetainUntilDate
+	}
+	return &types.ObjectLockRetention{Mode: mode, RetainUntilDate: &until}, true, nil
+}
+
+// setLegalHold sets the legal hold status of the objects.
+func (c *client) setLegalHold(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		if c.opts.dryrun {
+			return c.printf("(dryrun) set legal hold s3://%s/%s: %s\n", c.bucket, key, c.opts.legalHold)
+		}
+		_, err := c.svc.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+			Bucket: aws.String(c.bucket),
+			Key:    aws.String(key),
+			LegalHold: &types.ObjectLockLegalHold{
+				Status: c.opts.legalHold,
+			},
+			// S3 requires Content-MD5 or a checksum on the request.
+			ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set the legal hold of s3://%s/%s: %w", c.bucket, key, err)
+		}
+		return c.printf("set legal hold s3://%s/%s: %s\n", c.bucket, key, c.opts.legalHold)
+	})
+}
+
+// getRetention returns the retention of the object.
+// It returns the zero retention if the object has no retention.
+func (c *client) getRetention(ctx context.Context, key string) (*types.ObjectLockRetention, error) {
+	out, err := c.svc.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if isAPIError(err, errCodeNoLock) {
+		return &types.ObjectLockRetention{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the retention of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	if out.Retention == nil {
+		return &types.ObjectLockRetention{}, nil
+	}
+	return out.Retention, nil
+}
+
+// getLegalHold returns the legal hold status of the object.
+// It returns an empty status if the object has no legal hold.
+func (c *client) getLegalHold(ctx context.Context, key string) (types.ObjectLockLegalHoldStatus, error) {
+	out, err := c.svc.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if isAPIError(err, errCodeNoLock) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the legal hold of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	if out.LegalHold == nil {
+		return "", nil
+	}
+	return out.LegalHold.Status, nil
+}
+
+func (c *client) putRetention(ctx context.Context, key string, retention *types.ObjectLockRetention) error {
+	if c.opts.dryrun {
+		return c.printf("(dryrun) extend retention s3://%s/%s: %s until %s\n", c.bucket, key, retention.Mode, formatTime(retention.RetainUntilDate))
+	}
+	_, err := c.svc.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+		Bucket:    aws.String(c.bucket),
+		Key:       aws.String(key),
+		Retention: retention,
+		// S3 requires Content-MD5 or a checksum on the request.
+		ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extend the retention of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	return c.printf("extend retention s3://%s/%s: %s until %s\n", c.bucket, key, retention.Mode, formatTime(retention.RetainUntilDate))
+}
+
+func (c *client) printf(format string, a ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.w, format, a...)
+	return err
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func isAPIError(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
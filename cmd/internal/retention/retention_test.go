@@ -0,0 +1,264 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	date2030 = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2031 = time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2032 = time.Date(2032, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newFakeLockAPI() *testutils.FakeS3 {
+	f := testutils.NewFakeS3("a.txt", "logs/b.log", "logs/c.log")
+	f.Retentions["a.txt"] = types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeCompliance, RetainUntilDate: aws.Time(date2031)}
+	f.Retentions["logs/b.log"] = types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeGovernance, RetainUntilDate: aws.Time(date2030)}
+	f.LegalHolds["a.txt"] = types.ObjectLockLegalHoldStatusOn
+	return f
+}
+
+func newTestClient(svc objectAPI, key string, opts *options) (*client, *bytes.Buffer) {
+	var buf bytes.Buffer
+	if opts.batch.Concurrency == 0 {
+		opts.batch.Concurrency = 4
+	}
+	return &client{svc: svc, w: &buf, bucket: "bucket", key: key, opts: opts}, &buf
+}
+
+func sortedLines(s string) []string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestGet(t *testing.T) {
+	c, buf := newTestClient(newFakeLockAPI(), "a.txt", &options{})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "Mode:              COMPLIANCE\n" +
+		"Retain Until Date: 2031-01-01T00:00:00Z\n" +
+		"Legal Hold:        ON\n"
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestGet_Recursive(t *testing.T) {
+	c, buf := newTestClient(newFakeLockAPI(), "", &options{batch: batch.Options{Recursive: true}})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"s3://bucket/a.txt\tCOMPLIANCE\t2031-01-01T00:00:00Z\tON",
+		"s3://bucket/logs/b.log\tGOVERNANCE\t2030-01-01T00:00:00Z\t-",
+		"s3://bucket/logs/c.log\t-\t-\t-",
+	}
+	got := sortedLines(buf.String())
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	svc := newFakeLockAPI()
+	c, buf := newTestClient(svc, "", &options{
+		batch:       batch.Options{Recursive: true},
+		mode:        types.ObjectLockRetentionModeGovernance,
+		retainUntil: aws.Time(date2031),
+	})
+	if err := c.extend(context.Background()); err == nil {
+		t.Fatal("want an error for changing COMPLIANCE mode, got nil")
+	}
+
+	svc = newFakeLockAPI()
+	c, buf = newTestClient(svc, "logs/", &options{
+		batch:       batch.Options{Recursive: true},
+		mode:        types.ObjectLockRetentionModeGovernance,
+		retainUntil: aws.Time(date2031),
+	})
+	if err := c.extend(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"extend retention s3://bucket/logs/b.log: GOVERNANCE until 2031-01-01T00:00:00Z",
+		"extend retention s3://bucket/logs/c.log: GOVERNANCE until 2031-01-01T00:00:00Z",
+	}
+	got := sortedLines(buf.String())
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	for _, key := range []string{"logs/b.log", "logs/c.log"} {
+		if r := svc.Retentions[key]; !aws.ToTime(r.RetainUntilDate).Equal(date2031) {
+			t.Errorf("%s: want %v, got %v", key, date2031, r.RetainUntilDate)
+		}
+	}
+	for _, algo := range svc.LockChecksums {
+		if algo != types.ChecksumAlgorithmCrc32 {
+			t.Errorf("want CRC32 checksum, got %q", algo)
+		}
+	}
+}
+
+func TestExtend_Skip(t *testing.T) {
+	svc := newFakeLockAPI()
+	c, buf := newTestClient(svc, "a.txt", &options{retainUntil: aws.Time(date2030)})
+	if err := c.extend(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "skip s3://bucket/a.txt: COMPLIANCE until 2031-01-01T00:00:00Z\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if len(svc.LockChecksums) != 0 {
+		t.Errorf("want no updates, got %d", len(svc.LockChecksums))
+	}
+}
+
+func TestExtend_DryRun(t *testing.T) {
+	svc := newFakeLockAPI()
+	c, buf := newTestClient(svc, "a.txt", &options{retainUntil: aws.Time(date2032), dryrun: true})
+	if err := c.extend(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "(dryrun) extend retention s3://bucket/a.txt: COMPLIANCE until 2032-01-01T00:00:00Z\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if r := svc.Retentions["a.txt"]; !aws.ToTime(r.RetainUntilDate).Equal(date2031) {
+		t.Errorf("the retention is changed in the dry run: %v", r.RetainUntilDate)
+	}
+}
+
+func TestExtendRetention(t *testing.T) {
+	governance2031 := &types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeGovernance, RetainUntilDate: aws.Time(date2031)}
+	cases := []struct {
+		name    string
+		current *types.ObjectLockRetention
+		mode    types.ObjectLockRetentionMode
+		until   time.Time
+		want    *types.ObjectLockRetention
+		wantErr bool
+	}{
+		{
+			name:    "no retention",
+			current: &types.ObjectLockRetention{},
+			mode:    types.ObjectLockRetentionModeGovernance,
+			until:   date2030,
+			want:    &types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeGovernance, RetainUntilDate: aws.Time(date2030)},
+		},
+		{
+			name:    "no retention without mode",
+			current: &types.ObjectLockRetention{},
+			until:   date2030,
+			wantErr: true,
+		},
+		{
+			name:    "extend",
+			current: governance2031,
+			until:   date2032,
+			want:    &types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeGovernance, RetainUntilDate: aws.Time(date2032)},
+		},
+		{
+			name:    "already retained",
+			current: governance2031,
+			until:   date2030,
+		},
+		{
+			name:    "stricter mode keeps the date",
+			current: governance2031,
+			mode:    types.ObjectLockRetentionModeCompliance,
+			until:   date2030,
+			want:    &types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeCompliance, RetainUntilDate: aws.Time(date2031)},
+		},
+		{
+			name:    "weaker mode",
+			current: &types.ObjectLockRetention{Mode: types.ObjectLockRetentionModeCompliance, RetainUntilDate: aws.Time(date2030)},
+			mode:    types.ObjectLockRetentionModeGovernance,
+			until:   date2031,
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		got, ok, err := extendRetention(tc.current, tc.mode, tc.until)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if tc.want == nil {
+			if ok {
+				t.Errorf("%s: want skip, got %+v", tc.name, got)
+			}
+			continue
+		}
+		if !ok || got.Mode != tc.want.Mode || !aws.ToTime(got.RetainUntilDate).Equal(aws.ToTime(tc.want.RetainUntilDate)) {
+			t.Errorf("%s: want %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSetLegalHold(t *testing.T) {
+	svc := newFakeLockAPI()
+	c, buf := newTestClient(svc, "logs", &options{
+		batch:     batch.Options{Recursive: true},
+		legalHold: types.ObjectLockLegalHoldStatusOn,
+	})
+	if err := c.setLegalHold(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"set legal hold s3://bucket/logs/b.log: ON",
+		"set legal hold s3://bucket/logs/c.log: ON",
+	}
+	got := sortedLines(buf.String())
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if svc.LegalHolds["logs/c.log"] != types.ObjectLockLegalHoldStatusOn {
+		t.Errorf("want legal hold ON, got %q", svc.LegalHolds["logs/c.log"])
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts, err := parseOptions(testutils.NewCommand(t, InitExtend, "--retain-until-date", "2030-01-01T00:00:00Z", "--mode", "compliance"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.mode != types.ObjectLockRetentionModeCompliance || !opts.retainUntil.Equal(date2030) {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	cases := []struct {
+		init func(*cobra.Command)
+		args []string
+	}{
+		{InitExtend, []string{}},
+		{InitExtend, []string{"--retain-until-date", "2030-01-01"}},
+		{InitExtend, []string{"--retain-until-date", "2020-01-01T00:00:00Z"}},
+		{InitExtend, []string{"--retain-until-date", "2030-01-01T00:00:00Z", "--mode", "LEGAL"}},
+		{InitLegalHold, []string{}},
+		{InitLegalHold, []string{"--status", "MAYBE"}},
+	}
+	for _, tc := range cases {
+		if _, err := parseOptions(testutils.NewCommand(t, tc.init, tc.args...), now); err == nil {
+			t.Errorf("%v: want error, got nil", tc.args)
+		}
+	}
+}
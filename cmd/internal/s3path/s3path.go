@@ -0,0 +1,25 @@
+// Package s3path handles the S3 paths in the form of s3://bucket/key.
+package s3path
+
+import "strings"
+
+// Parse splits the S3 path into the bucket and the key.
+// The "s3://" prefix is optional.
+func Parse(path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "s3://")
+	if idx := strings.IndexByte(path, '/'); idx > 0 {
+		bucket = path[:idx]
+		key = path[idx+1:]
+		return
+	}
+	bucket = path
+	return
+}
+
+// NullableString returns nil if s is empty, for the optional parameters of the S3 API.
+func NullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
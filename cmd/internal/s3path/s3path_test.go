@@ -0,0 +1,32 @@
+package s3path
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		path   string
+		bucket string
+		key    string
+	}{
+		{"s3://bucket", "bucket", ""},
+		{"s3://bucket/", "bucket", ""},
+		{"s3://bucket/key", "bucket", "key"},
+		{"s3://bucket/dir/key", "bucket", "dir/key"},
+		{"bucket/key", "bucket", "key"},
+	}
+	for _, c := range cases {
+		bucket, key := Parse(c.path)
+		if bucket != c.bucket || key != c.key {
+			t.Errorf("%s: want (%q, %q), got (%q, %q)", c.path, c.bucket, c.key, bucket, key)
+		}
+	}
+}
+
+func TestNullableString(t *testing.T) {
+	if got := NullableString(""); got != nil {
+		t.Errorf("want nil, got %q", *got)
+	}
+	if got := NullableString("foo"); got == nil || *got != "foo" {
+		t.Errorf("want foo, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	cmd.Flags().String("version-id", "", "The version of the object.")
+}
+
+// objectAPI is the subset of S3 API that stat command uses.
+type objectAPI interface {
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// Run runs stat command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	versionID, err := cmd.Flags().GetString("version-id")
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || key == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := stat(ctx, svc, cmd.OutOrStdout(), cmd.ErrOrStderr(), bucket, key, versionID); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+// stat writes the headers, the metadata and the tags of the object to w.
+// The tags are optional, because the caller may not be allowed to read them.
+// The error of reading them is written to stderr.
+func stat(ctx context.Context, svc objectAPI, w, stderr io.Writer, bucket, key, versionID string) error {
+	head, err := svc.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(key),
+		VersionId:    s3path.NullableString(versionID),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	var tags []types.Tag
+	if aws.ToInt32(head.TagCount) > 0 {
+		resp, err := svc.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket:    aws.String(bucket),
+			Key:       aws.String(key),
+			VersionId: head.VersionId,
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, "Warning: failed to get the tags: ", err)
+		} else {
+			tags = resp.TagSet
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", name, value)
+		}
+	}
+
+	storageClass := string(head.StorageClass)
+	if storageClass == "" {
+		// HeadObject omits the storage class of STANDARD objects.
+		storageClass = string(types.StorageClassStandard)
+	}
+
+	field("Object", "s3://"+bucket+"/"+key)
+	field("Size", fmt.Sprint(aws.ToInt64(head.ContentLength)))
+	field("Last Modified", formatTime(head.LastModified))
+	field("ETag", aws.ToString(head.ETag))
+	field("Version ID", aws.ToString(head.VersionId))
+	field("Storage Class", storageClass)
+	field("Restore", aws.ToString(head.Restore))
+	field("Content-Type", aws.ToString(head.ContentType))
+	field("Content-Encoding", aws.ToString(head.ContentEncoding))
+	field("Content-Disposition", aws.ToString(head.ContentDisposition))
+	field("Content-Language", aws.ToString(head.ContentLanguage))
+	field("Cache-Control", aws.ToString(head.CacheControl))
+	field("Expires", formatTime(head.Expires))
+	field("Website Redirect", aws.ToString(head.WebsiteRedirectLocation))
+	field("Server-Side Encryption", string(head.ServerSideEncryption))
+	field("SSE KMS Key ID", aws.ToString(head.SSEKMSKeyId))
+	field("SSE Customer Algorithm", aws.ToString(head.SSECustomerAlgorithm))
+	if aws.ToBool(head.BucketKeyEnabled) {
+		field("Bucket Key", "enabled")
+	}
+	field("Checksum Type", string(head.ChecksumType))
+	field("Checksum CRC32", aws.ToString(head.ChecksumCRC32))
+	field("Checksum CRC32C", aws.ToString(head.ChecksumCRC32C))
+	field("Checksum CRC64NVME", aws.ToString(head.ChecksumCRC64NVME))
+	field("Checksum SHA1", aws.ToString(head.ChecksumSHA1))
+	field("Checksum SHA256", aws.ToString(head.ChecksumSHA256))
+	if n := aws.ToInt32(head.PartsCount); n > 0 {
+		field("Parts", fmt.Sprint(n))
+	}
+	field("Object Lock Mode", string(head.ObjectLockMode))
+	field("Object Lock Retain Until", formatTime(head.ObjectLockRetainUntilDate))
+	field("Object Lock Legal Hold", string(head.ObjectLockLegalHoldStatus))
+	field("Replication Status", string(head.ReplicationStatus))
+
+	if len(head.Metadata) > 0 {
+		fmt.Fprintln(tw, "Metadata:")
+		for _, k := range sortedKeys(head.Metadata) {
+			fmt.Fprintf(tw, "  %s:\t%s\n", k, head.Metadata[k])
+		}
+	}
+	if len(tags) > 0 {
+		m := make(map[string]string, len(tags))
+		for _, tag := range tags {
+			m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		fmt.Fprintln(tw, "Tags:")
+		for _, k := range sortedKeys(m) {
+			fmt.Fprintf(tw, "  %s:\t%s\n", k, m[k])
+		}
+	}
+	return tw.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(time.Local).Format("2006-01-02 15:04:05")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
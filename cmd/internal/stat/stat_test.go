@@ -0,0 +1,115 @@
+package stat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type fakeObjectAPI struct {
+	head       *s3.HeadObjectOutput
+	tags       []types.Tag
+	tagsErr    error
+	headInput  *s3.HeadObjectInput
+	tagsCalled bool
+}
+
+func (f *fakeObjectAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.headInput = params
+	return f.head, nil
+}
+
+func (f *fakeObjectAPI) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	f.tagsCalled = true
+	if f.tagsErr != nil {
+		return nil, f.tagsErr
+	}
+	return &s3.GetObjectTaggingOutput{TagSet: f.tags}, nil
+}
+
+func TestStat(t *testing.T) {
+	svc := &fakeObjectAPI{
+		head: &s3.HeadObjectOutput{
+			ContentLength:        aws.Int64(1234),
+			ETag:                 aws.String(`"etag"`),
+			VersionId:            aws.String("v1"),
+			ContentType:          aws.String("text/plain"),
+			ServerSideEncryption: types.ServerSideEncryptionAes256,
+			ChecksumCRC32:        aws.String("AAAAAA=="),
+			Metadata:             map[string]string{"b": "2", "a": "1"},
+			TagCount:             aws.Int32(1),
+		},
+		tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("infra")}},
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := stat(t.Context(), svc, &stdout, &stderr, "bucket", "key", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if svc.headInput.ChecksumMode != types.ChecksumModeEnabled {
+		t.Error("want checksum mode enabled")
+	}
+	if got := aws.ToString(svc.headInput.VersionId); got != "v1" {
+		t.Errorf("want version id v1, got %q", got)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{
+		"Object:", "s3://bucket/key",
+		"Size:", "1234",
+		"ETag:", `"etag"`,
+		"Version ID:", "v1",
+		"Storage Class:", "STANDARD",
+		"Content-Type:", "text/plain",
+		"Server-Side Encryption:", "AES256",
+		"Checksum CRC32:", "AAAAAA==",
+		"Metadata:",
+		"Tags:", "team:", "infra",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in the output:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "  a:") > strings.Index(out, "  b:") {
+		t.Errorf("the metadata is not sorted:\n%s", out)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected warning: %s", stderr.String())
+	}
+}
+
+func TestStat_NoTags(t *testing.T) {
+	svc := &fakeObjectAPI{
+		head: &s3.HeadObjectOutput{ContentLength: aws.Int64(0)},
+	}
+	var stdout, stderr bytes.Buffer
+	if err := stat(t.Context(), svc, &stdout, &stderr, "bucket", "key", ""); err != nil {
+		t.Fatal(err)
+	}
+	if svc.tagsCalled {
+		t.Error("GetObjectTagging should not be called for the objects without tags")
+	}
+}
+
+func TestStat_TagsDenied(t *testing.T) {
+	svc := &fakeObjectAPI{
+		head:    &s3.HeadObjectOutput{ContentLength: aws.Int64(0), TagCount: aws.Int32(1)},
+		tagsErr: errors.New("access denied"),
+	}
+	var stdout, stderr bytes.Buffer
+	if err := stat(t.Context(), svc, &stdout, &stderr, "bucket", "key", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "access denied") {
+		t.Errorf("want warning, got %q", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Size:") {
+		t.Errorf("want the headers even if the tags are denied, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,380 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxTags is the maximum number of the tags of an object.
+const maxTags = 10
+
+// objectAPI is the subset of the S3 API that tag command uses.
+type objectAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+	DeleteObjectTagging(ctx context.Context, params *s3.DeleteObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectTaggingOutput, error)
+}
+
+// InitGet initializes flags of tag get command.
+func InitGet(cmd *cobra.Command) {
+	initCommon(cmd.Flags())
+}
+
+// InitSet initializes flags of tag set command.
+func InitSet(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	initCommon(flags)
+	flags.StringToString("tags", nil, "The tags to set. e.g. --tags Key1=Value1,Key2=Value2")
+	flags.Bool("merge", false, "Merges the tags into the existing tags. This is the default.")
+	flags.Bool("replace", false, "Replaces the existing tags with the tags.")
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+}
+
+// InitDelete initializes flags of tag delete command.
+func InitDelete(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	initCommon(flags)
+	flags.StringSlice("keys", nil, "The keys of the tags to delete. All tags are deleted if it is omitted.")
+	flags.Bool("dryrun", false, "Displays the operations that would be performed using the specified command without actually running them.")
+}
+
+func initCommon(flags *pflag.FlagSet) {
+	flags.Bool("recursive", false, "Command is performed on all objects under the specified prefix.")
+	filter.AddFlags(flags)
+}
+
+// options is the options of tag commands.
+type options struct {
+	batch batch.Options
+
+	// tags is the tags to set, sorted by the keys.
+	tags    []types.Tag
+	replace bool
+
+	// keys is the keys of the tags to delete.
+	keys []string
+
+	dryrun bool
+}
+
+// RunGet runs tag get command.
+func RunGet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.get(ctx)
+	})
+}
+
+// RunSet runs tag set command.
+func RunSet(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.set(ctx)
+	})
+}
+
+// RunDelete runs tag delete command.
+func RunDelete(cmd *cobra.Command, args []string) {
+	run(cmd, args, func(ctx context.Context, c *client) error {
+		return c.delete(ctx)
+	})
+}
+
+func run(cmd *cobra.Command, args []string, fn func(ctx context.Context, c *client) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, key := s3path.Parse(args[0])
+	if bucket == "" || (key == "" && !opts.batch.Recursive) {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/key")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	c := &client{
+		svc:    svc,
+		w:      cmd.OutOrStdout(),
+		bucket: bucket,
+		key:    key,
+		opts:   opts,
+	}
+	if err := fn(ctx, c); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	recursive, err := flags.GetBool("recursive")
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter.FromFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	opts := &options{
+		batch: batch.Options{
+			Recursive:   recursive,
+			Filter:      f,
+			Concurrency: concurrency,
+		},
+	}
+
+	if flags.Lookup("tags") != nil {
+		tags, err := flags.GetStringToString("tags")
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			return nil, errors.New("--tags is required")
+		}
+		if len(tags) > maxTags {
+			return nil, fmt.Errorf("too many tags: %d, an object can have up to %d tags", len(tags), maxTags)
+		}
+		opts.tags = parseTags(tags)
+
+		merge, err := flags.GetBool("merge")
+		if err != nil {
+			return nil, err
+		}
+		replace, err := flags.GetBool("replace")
+		if err != nil {
+			return nil, err
+		}
+		if merge && replace {
+			return nil, errors.New("--merge and --replace cannot be specified at the same time")
+		}
+		opts.replace = replace
+	}
+
+	if flags.Lookup("keys") != nil {
+		keys, err := flags.GetStringSlice("keys")
+		if err != nil {
+			return nil, err
+		}
+		opts.keys = keys
+	}
+
+	if flags.Lookup("dryrun") != nil {
+		dryrun, err := flags.GetBool("dryrun")
+		if err != nil {
+			return nil, err
+		}
+		opts.dryrun = dryrun
+	}
+	return opts, nil
+}
+
+type client struct {
+	svc    objectAPI
+	bucket string
+	key    string
+	opts   *options
+
+	// mu protects w from the concurrent writes.
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// get prints the tags of the object.
+// In the recursive mode, it prints the tags of each object in a line.
+func (c *client) get(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		tags, err := c.getTags(ctx, key)
+		if err != nil {
+			return err
+		}
+		sortTags(tags)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.opts.batch.Recursive {
+			for _, t := range tags {
+				if _, err := fmt.Fprintf(c.w, "%s=%s\n", aws.ToString(t.Key), aws.ToString(t.Value)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		_, err = fmt.Fprintf(c.w, "s3://%s/%s\t%s\n", c.bucket, key, formatTags(tags))
+		return err
+	})
+}
+
+// set sets the tags to the objects.
+func (c *client) set(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		tags := slices.Clone(c.opts.tags)
+		if !c.opts.replace {
+			current, err := c.getTags(ctx, key)
+			if err != nil {
+				return err
+			}
+			tags = mergeTags(current, tags)
+			if len(tags) > maxTags {
+				return fmt.Errorf("s3://%s/%s: too many tags after merging: %d, an object can have up to %d tags", c.bucket, key, len(tags), maxTags)
+			}
+		}
+		return c.putTags(ctx, key, tags)
+	})
+}
+
+// delete deletes the tags from the objects.
+// If opts.keys is empty, all tags are deleted.
+func (c *client) delete(ctx context.Context) error {
+	return batch.Run(ctx, c.svc, c.bucket, c.key, c.opts.batch, func(ctx context.Context, key string) error {
+		if len(c.opts.keys) == 0 {
+			return c.deleteTags(ctx, key)
+		}
+
+		current, err := c.getTags(ctx, key)
+		if err != nil {
+			return err
+		}
+		tags := slices.DeleteFunc(current, func(t types.Tag) bool {
+			return slices.Contains(c.opts.keys, aws.ToString(t.Key))
+		})
+		if len(tags) == 0 {
+			return c.deleteTags(ctx, key)
+		}
+		return c.putTags(ctx, key, tags)
+	})
+}
+
+func (c *client) getTags(ctx context.Context, key string) ([]types.Tag, error) {
+	out, err := c.svc.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the tags of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	return out.TagSet, nil
+}
+
+func (c *client) putTags(ctx context.Context, key string, tags []types.Tag) error {
+	sortTags(tags)
+	if c.opts.dryrun {
+		return c.printf("(dryrun) set tags s3://%s/%s: %s\n", c.bucket, key, formatTags(tags))
+	}
+	_, err := c.svc.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+		Tagging: &types.Tagging{
+			TagSet: tags,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the tags of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	return c.printf("set tags s3://%s/%s: %s\n", c.bucket, key, formatTags(tags))
+}
+
+func (c *client) deleteTags(ctx context.Context, key string) error {
+	if c.opts.dryrun {
+		return c.printf("(dryrun) delete tags s3://%s/%s\n", c.bucket, key)
+	}
+	_, err := c.svc.DeleteObjectTagging(ctx, &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete the tags of s3://%s/%s: %w", c.bucket, key, err)
+	}
+	return c.printf("delete tags s3://%s/%s\n", c.bucket, key)
+}
+
+func (c *client) printf(format string, a ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.w, format, a...)
+	return err
+}
+
+// mergeTags returns the tags that the new tags are merged into the current tags.
+// The new tags overwrite the current tags with the same keys.
+func mergeTags(current, tags []types.Tag) []types.Tag {
+	ret := make([]types.Tag, 0, len(current)+len(tags))
+	for _, t := range current {
+		if !slices.ContainsFunc(tags, func(u types.Tag) bool { return aws.ToString(u.Key) == aws.ToString(t.Key) }) {
+			ret = append(ret, t)
+		}
+	}
+	return append(ret, tags...)
+}
+
+func sortTags(tags []types.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		return aws.ToString(tags[i].Key) < aws.ToString(tags[j].Key)
+	})
+}
+
+// formatTags formats the tags in the form of "Key1=Value1,Key2=Value2".
+func formatTags(tags []types.Tag) string {
+	var b strings.Builder
+	for i, t := range tags {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(aws.ToString(t.Key))
+		b.WriteByte('=')
+		b.WriteString(aws.ToString(t.Value))
+	}
+	return b.String()
+}
+
+func parseTags(tags map[string]string) []types.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		ret = append(ret, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(tags[k]),
+		})
+	}
+	return ret
+}
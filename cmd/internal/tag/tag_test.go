@@ -0,0 +1,195 @@
+package tag
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/shogo82148/s3cli-mini/cmd/internal/batch"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/filter"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+	"github.com/spf13/cobra"
+)
+
+func newFakeTagAPI() *testutils.FakeS3 {
+	f := testutils.NewFakeS3("a.txt", "logs/b.log", "logs/c.txt")
+	f.Tags["a.txt"] = map[string]string{"env": "prod", "owner": "alice"}
+	f.Tags["logs/b.log"] = map[string]string{"env": "dev"}
+	return f
+}
+
+func newTestClient(svc objectAPI, key string, opts *options) (*client, *bytes.Buffer) {
+	var buf bytes.Buffer
+	opts.batch.Concurrency = 2
+	return &client{svc: svc, w: &buf, bucket: "bucket", key: key, opts: opts}, &buf
+}
+
+func TestGet(t *testing.T) {
+	svc := newFakeTagAPI()
+	c, buf := newTestClient(svc, "a.txt", &options{})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "env=prod\nowner=alice\n"
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestGet_Recursive(t *testing.T) {
+	svc := newFakeTagAPI()
+	c, buf := newTestClient(svc, "", &options{batch: batch.Options{Recursive: true}})
+	if err := c.get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+	want := []string{
+		"s3://bucket/a.txt\tenv=prod,owner=alice",
+		"s3://bucket/logs/b.log\tenv=dev",
+		"s3://bucket/logs/c.txt\t",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, lines)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tags := parseTags(map[string]string{"env": "stg", "team": "web"})
+
+	t.Run("merge", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{tags: tags})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"env": "stg", "owner": "alice", "team": "web"}
+		if got := svc.Tags["a.txt"]; !maps.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{tags: tags, replace: true})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"env": "stg", "team": "web"}
+		if got := svc.Tags["a.txt"]; !maps.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("recursive with filter", func(t *testing.T) {
+		cmd := testutils.NewCommand(t, func(cmd *cobra.Command) { filter.AddFlags(cmd.Flags()) }, "--exclude", "*.txt")
+		f, err := filter.FromFlags(cmd.Flags())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "logs", &options{batch: batch.Options{Recursive: true, Filter: f}, tags: tags})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := svc.Tags["logs/b.log"], map[string]string{"env": "stg", "team": "web"}; !maps.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+		if got := svc.Tags["logs/c.txt"]; len(got) != 0 {
+			t.Errorf("logs/c.txt must be excluded, got %v", got)
+		}
+		if got := svc.Tags["a.txt"]; got["env"] != "prod" {
+			t.Errorf("a.txt is not under the prefix, got %v", got)
+		}
+	})
+
+	t.Run("dryrun", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, buf := newTestClient(svc, "a.txt", &options{tags: tags, dryrun: true})
+		if err := c.set(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := "(dryrun) set tags s3://bucket/a.txt: env=stg,owner=alice,team=web\n"
+		if got := buf.String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		if got := svc.Tags["a.txt"]["env"]; got != "prod" {
+			t.Errorf("the tags must not be changed in the dry run mode, got %q", got)
+		}
+	})
+
+	t.Run("too many tags", func(t *testing.T) {
+		many := map[string]string{}
+		for _, k := range strings.Split("a,b,c,d,e,f,g,h,i", ",") {
+			many[k] = k
+		}
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{tags: parseTags(many)})
+		if err := c.set(context.Background()); err == nil {
+			t.Error("want error, got nil")
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{})
+		if err := c.delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := svc.Tags["a.txt"]; len(got) != 0 {
+			t.Errorf("want no tags, got %v", got)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, _ := newTestClient(svc, "a.txt", &options{keys: []string{"owner"}})
+		if err := c.delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := svc.Tags["a.txt"], map[string]string{"env": "prod"}; !maps.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("dryrun", func(t *testing.T) {
+		svc := newFakeTagAPI()
+		c, buf := newTestClient(svc, "a.txt", &options{dryrun: true})
+		if err := c.delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := "(dryrun) delete tags s3://bucket/a.txt\n"
+		if got := buf.String(); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		if got := svc.Tags["a.txt"]; len(got) != 2 {
+			t.Errorf("the tags must not be changed in the dry run mode, got %v", got)
+		}
+	})
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := parseOptions(testutils.NewCommand(t, InitSet, "--tags", "b=2,a=1", "--replace", "--recursive", "--include", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatTags(opts.tags); got != "a=1,b=2" {
+		t.Errorf("want tags a=1,b=2, got %s", got)
+	}
+	if !opts.replace || !opts.batch.Recursive {
+		t.Errorf("want replace and recursive, got %#v", opts)
+	}
+
+	if _, err := parseOptions(testutils.NewCommand(t, InitSet)); err == nil {
+		t.Error("want error for missing --tags, got nil")
+	}
+	if _, err := parseOptions(testutils.NewCommand(t, InitSet, "--tags", "a=1", "--merge", "--replace")); err == nil {
+		t.Error("want error for --merge and --replace, got nil")
+	}
+}
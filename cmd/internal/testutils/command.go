@@ -0,0 +1,18 @@
+package testutils
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns a command with the flags initialized by init, and parses args.
+func NewCommand(t testing.TB, init func(*cobra.Command), args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	init(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
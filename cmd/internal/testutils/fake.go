@@ -0,0 +1,246 @@
+package testutils
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// FakeS3 keeps a bucket in memory.
+// It is used for unit tests of the commands.
+type FakeS3 struct {
+	mu sync.Mutex
+
+	// PageSize is the maximum number of the keys and the common prefixes in a page of ListObjectsV2.
+	// Zero means 1000, the same as S3.
+	PageSize int
+
+	// Objects is the objects in the bucket, keyed by their keys.
+	Objects map[string]types.Object
+
+	// Tags is the tags of the objects, keyed by their keys.
+	Tags map[string]map[string]string
+
+	// Deleted is the keys deleted by DeleteObjects, and DeleteBatches is the number of its calls.
+	Deleted       []string
+	DeleteBatches int
+
+	// Ownership is the object ownership of the bucket. Empty means no ownership controls.
+	Ownership types.ObjectOwnership
+
+	// ACLs is the ACLs of the objects that GetObjectAcl returns, keyed by their keys.
+	ACLs map[string]*s3.GetObjectAclOutput
+
+	// ACLInputs is the last inputs of PutObjectAcl, keyed by the keys of the objects.
+	ACLInputs map[string]*s3.PutObjectAclInput
+
+	// Retentions and LegalHolds are the Object Lock settings of the objects, keyed by their keys.
+	Retentions map[string]types.ObjectLockRetention
+	LegalHolds map[string]types.ObjectLockLegalHoldStatus
+
+	// LockChecksums is the checksum algorithms of the requests that update the Object Lock settings.
+	LockChecksums []types.ChecksumAlgorithm
+}
+
+// NewFakeS3 returns a bucket with the empty objects.
+func NewFakeS3(keys ...string) *FakeS3 {
+	f := &FakeS3{
+		Objects:   map[string]types.Object{},
+		Tags:      map[string]map[string]string{},
+		ACLs:      map[string]*s3.GetObjectAclOutput{},
+		ACLInputs: map[string]*s3.PutObjectAclInput{},
+
+		Retentions: map[string]types.ObjectLockRetention{},
+		LegalHolds: map[string]types.ObjectLockLegalHoldStatus{},
+	}
+	for _, key := range keys {
+		f.AddObject(types.Object{Key: aws.String(key), Size: aws.Int64(0)})
+	}
+	return f
+}
+
+// AddObject adds the object to the bucket.
+func (f *FakeS3) AddObject(obj types.Object) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Objects[aws.ToString(obj.Key)] = obj
+}
+
+// ListObjectsV2 lists the objects in the key order.
+// The continuation token is the key that the next page starts from.
+func (f *FakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	start := aws.ToString(params.ContinuationToken)
+	pageSize := f.PageSize
+	if pageSize == 0 {
+		pageSize = 1000
+	}
+
+	keys := slices.Sorted(func(yield func(string) bool) {
+		for key := range f.Objects {
+			if strings.HasPrefix(key, prefix) && key >= start && !yield(key) {
+				return
+			}
+		}
+	})
+
+	// a common prefix rolls up all keys under it, and is returned only once.
+	out := &s3.ListObjectsV2Output{}
+	var lastPrefix string
+	n := 0
+	for _, key := range keys {
+		if lastPrefix != "" && strings.HasPrefix(key, lastPrefix) {
+			continue
+		}
+		if n == pageSize {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(key)
+			break
+		}
+		n++
+		if delimiter != "" {
+			if idx := strings.Index(key[len(prefix):], delimiter); idx >= 0 {
+				lastPrefix = key[:len(prefix)+idx+len(delimiter)]
+				out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(lastPrefix)})
+				continue
+			}
+		}
+		out.Contents = append(out.Contents, f.Objects[key])
+	}
+	out.KeyCount = aws.Int32(int32(n))
+	return out, nil
+}
+
+// GetObjectTagging returns the tags of the object, sorted by the keys.
+func (f *FakeS3) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tags := f.Tags[aws.ToString(params.Key)]
+	out := &s3.GetObjectTaggingOutput{TagSet: []types.Tag{}}
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		out.TagSet = append(out.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return out, nil
+}
+
+// DeleteObjects deletes the objects, and records their keys.
+func (f *FakeS3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.DeleteBatches++
+	out := &s3.DeleteObjectsOutput{}
+	for _, obj := range params.Delete.Objects {
+		key := aws.ToString(obj.Key)
+		delete(f.Objects, key)
+		delete(f.Tags, key)
+		f.Deleted = append(f.Deleted, key)
+		out.Deleted = append(out.Deleted, types.DeletedObject{Key: obj.Key})
+	}
+	return out, nil
+}
+
+// PutObjectTagging replaces the tags of the object.
+func (f *FakeS3) PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tags := map[string]string{}
+	for _, t := range params.Tagging.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	f.Tags[aws.ToString(params.Key)] = tags
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+// DeleteObjectTagging removes all the tags of the object.
+func (f *FakeS3) DeleteObjectTagging(ctx context.Context, params *s3.DeleteObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectTaggingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Tags[aws.ToString(params.Key)] = map[string]string{}
+	return &s3.DeleteObjectTaggingOutput{}, nil
+}
+
+// GetBucketOwnershipControls returns the object ownership of the bucket.
+func (f *FakeS3) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Ownership == "" {
+		return nil, &smithy.GenericAPIError{Code: "OwnershipControlsNotFoundError"}
+	}
+	return &s3.GetBucketOwnershipControlsOutput{
+		OwnershipControls: &types.OwnershipControls{
+			Rules: []types.OwnershipControlsRule{{ObjectOwnership: f.Ownership}},
+		},
+	}, nil
+}
+
+// GetObjectAcl returns the ACL of the object.
+func (f *FakeS3) GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if out, ok := f.ACLs[aws.ToString(params.Key)]; ok {
+		return out, nil
+	}
+	return &s3.GetObjectAclOutput{}, nil
+}
+
+// PutObjectAcl records the input.
+func (f *FakeS3) PutObjectAcl(ctx context.Context, params *s3.PutObjectAclInput, optFns ...func(*s3.Options)) (*s3.PutObjectAclOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ACLInputs[aws.ToString(params.Key)] = params
+	return &s3.PutObjectAclOutput{}, nil
+}
+
+// errNoLock is the error of the objects without the Object Lock settings.
+var errNoLock = &smithy.GenericAPIError{Code: "NoSuchObjectLockConfiguration"}
+
+// GetObjectRetention returns the retention of the object.
+func (f *FakeS3) GetObjectRetention(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.Retentions[aws.ToString(params.Key)]
+	if !ok {
+		return nil, errNoLock
+	}
+	return &s3.GetObjectRetentionOutput{Retention: &r}, nil
+}
+
+// PutObjectRetention replaces the retention of the object.
+func (f *FakeS3) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Retentions[aws.ToString(params.Key)] = *params.Retention
+	f.LockChecksums = append(f.LockChecksums, params.ChecksumAlgorithm)
+	return &s3.PutObjectRetentionOutput{}, nil
+}
+
+// GetObjectLegalHold returns the legal hold status of the object.
+func (f *FakeS3) GetObjectLegalHold(ctx context.Context, params *s3.GetObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.GetObjectLegalHoldOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.LegalHolds[aws.ToString(params.Key)]
+	if !ok {
+		return nil, errNoLock
+	}
+	return &s3.GetObjectLegalHoldOutput{LegalHold: &types.ObjectLockLegalHold{Status: status}}, nil
+}
+
+// PutObjectLegalHold replaces the legal hold status of the object.
+func (f *FakeS3) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.LegalHolds[aws.ToString(params.Key)] = params.LegalHold.Status
+	f.LockChecksums = append(f.LockChecksums, params.ChecksumAlgorithm)
+	return &s3.PutObjectLegalHoldOutput{}, nil
+}
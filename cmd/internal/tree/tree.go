@@ -0,0 +1,243 @@
+package tree
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/interfaces"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/ls"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/s3path"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.IntP("level", "L", 0, "Descends only N levels of the prefixes. 0 means no limit.")
+	flags.BoolP("dirs-only", "d", false, "Displays the prefixes only.")
+	flags.BoolP("size", "s", false, "Displays the sizes of the objects.")
+	flags.Bool("human-readable", false, "Displays sizes in human readable format.")
+}
+
+// options is the options of tree command.
+type options struct {
+	level         int
+	dirsOnly      bool
+	size          bool
+	humanReadable bool
+	concurrency   int
+}
+
+// Run runs tree command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket, prefix := s3path.Parse(args[0])
+	if bucket == "" {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket/prefix")
+		os.Exit(1)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	root, err := walk(ctx, svc, bucket, prefix, opts)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	printTree(w, "s3://"+bucket+"/"+prefix, root, opts)
+	if err := w.Flush(); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	level, err := flags.GetInt("level")
+	if err != nil {
+		return nil, err
+	}
+	if level < 0 {
+		return nil, fmt.Errorf("invalid level: %d", level)
+	}
+	dirsOnly, err := flags.GetBool("dirs-only")
+	if err != nil {
+		return nil, err
+	}
+	size, err := flags.GetBool("size")
+	if err != nil {
+		return nil, err
+	}
+	humanReadable, err := flags.GetBool("human-readable")
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := config.MaxConcurrentRequests()
+	if err != nil {
+		return nil, err
+	}
+	return &options{
+		level:         level,
+		dirsOnly:      dirsOnly,
+		size:          size,
+		humanReadable: humanReadable,
+		concurrency:   concurrency,
+	}, nil
+}
+
+// dir is a prefix in the tree.
+type dir struct {
+	prefix  string
+	entries []entry
+}
+
+// entry is an object or a common prefix in dir, in the order of ls.
+type entry struct {
+	name string
+	size int64
+
+	// dir is nil for objects.
+	dir *dir
+}
+
+// walk lists the prefixes level by level.
+// The prefixes in the same level are listed concurrently.
+func walk(ctx context.Context, svc interfaces.ObjectListerV2, bucket, prefix string, opts *options) (*dir, error) {
+	root := &dir{prefix: prefix}
+	level := []*dir{root}
+	for depth := 0; len(level) > 0 && (opts.level == 0 || depth < opts.level); depth++ {
+		g, ctx := errgroup.WithContext(ctx)
+		g.SetLimit(max(opts.concurrency, 1))
+		for _, d := range level {
+			g.Go(func() error {
+				return list(ctx, svc, bucket, d)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		var next []*dir
+		for _, d := range level {
+			for _, e := range d.entries {
+				if e.dir != nil {
+					next = append(next, e.dir)
+				}
+			}
+		}
+		level = next
+	}
+	return root, nil
+}
+
+// list lists the objects and the common prefixes just under d.
+func list(ctx context.Context, svc interfaces.ObjectListerV2, bucket string, d *dir) error {
+	p := s3.NewListObjectsV2Paginator(svc, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(d.prefix),
+		Delimiter: aws.String("/"),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		ls.Merge(page.Contents, page.CommonPrefixes, func(obj types.Object) {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), d.prefix)
+			if name == "" {
+				// the placeholder object of the directory, e.g. "foo/" created by the S3 console.
+				return
+			}
+			d.entries = append(d.entries, entry{
+				name: name,
+				size: aws.ToInt64(obj.Size),
+			})
+		}, func(prefix types.CommonPrefix) {
+			p := aws.ToString(prefix.Prefix)
+			d.entries = append(d.entries, entry{
+				name: strings.TrimPrefix(p, d.prefix),
+				dir:  &dir{prefix: p},
+			})
+		})
+	}
+	return nil
+}
+
+// printTree writes the tree in the format of tree(1).
+func printTree(w io.Writer, name string, root *dir, opts *options) {
+	var dirs, objects int
+	var visit func(d *dir, indent string)
+	visit = func(d *dir, indent string) {
+		entries := d.entries
+		if opts.dirsOnly {
+			entries = make([]entry, 0, len(d.entries))
+			for _, e := range d.entries {
+				if e.dir != nil {
+					entries = append(entries, e)
+				}
+			}
+		}
+		for i, e := range entries {
+			branch, next := "├── ", "│   "
+			if i == len(entries)-1 {
+				branch, next = "└── ", "    "
+			}
+			if e.dir != nil {
+				dirs++
+				fmt.Fprintf(w, "%s%s%s\n", indent, branch, e.name)
+				visit(e.dir, indent+next)
+				continue
+			}
+			objects++
+			if opts.size {
+				fmt.Fprintf(w, "%s%s[%10s]  %s\n", indent, branch, formatSize(e.size, opts), e.name)
+			} else {
+				fmt.Fprintf(w, "%s%s%s\n", indent, branch, e.name)
+			}
+		}
+	}
+
+	fmt.Fprintln(w, name)
+	visit(root, "")
+	if opts.dirsOnly {
+		fmt.Fprintf(w, "\n%d directories\n", dirs)
+	} else {
+		fmt.Fprintf(w, "\n%d directories, %d objects\n", dirs, objects)
+	}
+}
+
+func formatSize(size int64, opts *options) string {
+	if opts.humanReadable {
+		return ls.MakeHumanReadable(size)
+	}
+	return fmt.Sprint(size)
+}
@@ -0,0 +1,117 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/testutils"
+)
+
+// newFakeLister returns a bucket with the objects, that lists two keys per page.
+func newFakeLister(sizes map[string]int64) *testutils.FakeS3 {
+	f := testutils.NewFakeS3()
+	f.PageSize = 2
+	for key, size := range sizes {
+		f.AddObject(types.Object{
+			Key:  aws.String(key),
+			Size: aws.Int64(size),
+		})
+	}
+	return f
+}
+
+var testObjects = map[string]int64{
+	"a.txt":         1,
+	"foo/":          0,
+	"foo/a.txt":     10,
+	"foo/bar/a.txt": 100,
+	"foo/bar/b.txt": 200,
+	"foo/baz/a.txt": 1024,
+	"qux/a.txt":     2048,
+	"z.txt":         3,
+}
+
+func runTree(t *testing.T, opts *options) string {
+	t.Helper()
+	opts.concurrency = 2
+	root, err := walk(t.Context(), newFakeLister(testObjects), "bucket", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printTree(&buf, "s3://bucket/", root, opts)
+	return buf.String()
+}
+
+func TestTree(t *testing.T) {
+	got := runTree(t, &options{})
+	want := `s3://bucket/
+├── a.txt
+├── foo/
+│   ├── a.txt
+│   ├── bar/
+│   │   ├── a.txt
+│   │   └── b.txt
+│   └── baz/
+│       └── a.txt
+├── qux/
+│   └── a.txt
+└── z.txt
+
+4 directories, 7 objects
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTree_Level(t *testing.T) {
+	got := runTree(t, &options{level: 1})
+	want := `s3://bucket/
+├── a.txt
+├── foo/
+├── qux/
+└── z.txt
+
+2 directories, 2 objects
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTree_DirsOnly(t *testing.T) {
+	got := runTree(t, &options{dirsOnly: true})
+	want := `s3://bucket/
+├── foo/
+│   ├── bar/
+│   └── baz/
+└── qux/
+
+4 directories
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTree_Size(t *testing.T) {
+	got := runTree(t, &options{level: 2, size: true, humanReadable: true})
+	want := `s3://bucket/
+├── [    1 Byte]  a.txt
+├── foo/
+│   ├── [  10 Bytes]  a.txt
+│   ├── bar/
+│   └── baz/
+├── qux/
+│   └── [   2.0 KiB]  a.txt
+└── [   3 Bytes]  z.txt
+
+4 directories, 4 objects
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,112 @@
+package website
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// websiteAPI is the subset of the S3 API that website command uses.
+type websiteAPI interface {
+	PutBucketWebsite(ctx context.Context, params *s3.PutBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error)
+}
+
+// Init initializes flags.
+func Init(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.String("index-document", "", "A suffix that is appended to a request that is for a directory on the website endpoint, e.g. index.html.")
+	flags.String("error-document", "", "The object key name to use when a 4XX class error occurs.")
+}
+
+// options is the options of website command.
+type options struct {
+	indexDocument string
+	errorDocument string
+}
+
+// Run runs website command.
+func Run(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(args) != 1 {
+		if err := cmd.Usage(); err != nil {
+			cmd.PrintErrln("error: ", err)
+		}
+		os.Exit(1)
+	}
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		cmd.PrintErrln("Validation error: ", err)
+		os.Exit(1)
+	}
+	bucket := strings.TrimSuffix(strings.TrimPrefix(args[0], "s3://"), "/")
+	if bucket == "" || strings.Contains(bucket, "/") {
+		cmd.PrintErrln("Validation error: the path must be s3://bucket")
+		os.Exit(1)
+	}
+
+	svc, err := config.NewS3BucketClient(ctx, bucket)
+	if err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+	if err := website(ctx, svc, cmd.OutOrStdout(), bucket, opts); err != nil {
+		cmd.PrintErrln("Error: ", err)
+		os.Exit(1)
+	}
+}
+
+func parseOptions(cmd *cobra.Command) (*options, error) {
+	flags := cmd.Flags()
+	indexDocument, err := flags.GetString("index-document")
+	if err != nil {
+		return nil, err
+	}
+	if indexDocument == "" {
+		return nil, errors.New("--index-document is required")
+	}
+	if strings.Contains(indexDocument, "/") {
+		return nil, fmt.Errorf("invalid index document: %s, it must not contain slashes", indexDocument)
+	}
+	errorDocument, err := flags.GetString("error-document")
+	if err != nil {
+		return nil, err
+	}
+	return &options{
+		indexDocument: indexDocument,
+		errorDocument: strings.TrimPrefix(errorDocument, "/"),
+	}, nil
+}
+
+// website configures the bucket as a static website.
+func website(ctx context.Context, svc websiteAPI, w io.Writer, bucket string, opts *options) error {
+	conf := &types.WebsiteConfiguration{
+		IndexDocument: &types.IndexDocument{
+			Suffix: aws.String(opts.indexDocument),
+		},
+	}
+	if opts.errorDocument != "" {
+		conf.ErrorDocument = &types.ErrorDocument{
+			Key: aws.String(opts.errorDocument),
+		}
+	}
+	_, err := svc.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+		Bucket:               aws.String(bucket),
+		WebsiteConfiguration: conf,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure the website of s3://%s: %w", bucket, err)
+	}
+	_, err = fmt.Fprintf(w, "website: s3://%s\n", bucket)
+	return err
+}
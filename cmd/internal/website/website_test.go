@@ -0,0 +1,80 @@
+package website
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/spf13/cobra"
+)
+
+type fakeWebsiteAPI struct {
+	input *s3.PutBucketWebsiteInput
+}
+
+func (f *fakeWebsiteAPI) PutBucketWebsite(ctx context.Context, params *s3.PutBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error) {
+	f.input = params
+	return &s3.PutBucketWebsiteOutput{}, nil
+}
+
+func newTestCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	Init(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestWebsite(t *testing.T) {
+	opts, err := parseOptions(newTestCommand(t, "--index-document", "index.html", "--error-document", "/404.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &fakeWebsiteAPI{}
+	var buf bytes.Buffer
+	if err := website(context.Background(), svc, &buf, "bucket", opts); err != nil {
+		t.Fatal(err)
+	}
+	conf := svc.input.WebsiteConfiguration
+	if got := aws.ToString(conf.IndexDocument.Suffix); got != "index.html" {
+		t.Errorf("want index.html, got %s", got)
+	}
+	if got := aws.ToString(conf.ErrorDocument.Key); got != "404.html" {
+		t.Errorf("want 404.html, got %s", got)
+	}
+	if got, want := buf.String(), "website: s3://bucket\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWebsite_NoErrorDocument(t *testing.T) {
+	opts, err := parseOptions(newTestCommand(t, "--index-document", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeWebsiteAPI{}
+	if err := website(context.Background(), svc, &bytes.Buffer{}, "bucket", opts); err != nil {
+		t.Fatal(err)
+	}
+	if svc.input.WebsiteConfiguration.ErrorDocument != nil {
+		t.Errorf("want no error document, got %v", svc.input.WebsiteConfiguration.ErrorDocument)
+	}
+}
+
+func TestParseOptions_Invalid(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"--error-document", "404.html"},
+		{"--index-document", "docs/index.html"},
+	}
+	for _, args := range cases {
+		if _, err := parseOptions(newTestCommand(t, args...)); err == nil {
+			t.Errorf("%v: want error, got nil", args)
+		}
+	}
+}
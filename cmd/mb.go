@@ -29,8 +29,39 @@ import (
 var mbCmd = &cobra.Command{
 	Use:   "mb",
 	Short: "Creates an S3 bucket.",
-	Long:  `Creates an S3 bucket.`,
-	Run:   mb.Run,
+	Long: `Creates an S3 bucket.
+
+Synopsis
+mb
+<S3Uri>
+[--bucket-namespace <value>]
+[--object-lock-enabled]
+[--object-ownership <value>]
+[--block-public-access]
+[--versioning]
+[--default-encryption <value>]
+[--tags <value>]
+
+Options
+path (string)
+
+--bucket-namespace (string) The namespace for the bucket. global or account-regional.
+
+--object-lock-enabled (boolean) Enables S3 Object Lock for the bucket.
+
+--object-ownership (string) The object ownership setting of the bucket. BucketOwnerEnforced, BucketOwnerPreferred or ObjectWriter.
+
+--block-public-access (boolean) Blocks all public access to the bucket and its objects.
+
+--versioning (boolean) Enables versioning for the bucket.
+
+--default-encryption (string) The default server-side encryption of the bucket. AES256, aws:kms or aws:kms:<key-id>.
+
+--tags (string) The tags of the bucket. e.g. Key1=Value1,Key2=Value2
+
+The bucket is created in the region specified by --region, or the region of the profile.
+If applying any configuration fails, the bucket is deleted.`,
+	Run: mb.Run,
 }
 
 func init() {
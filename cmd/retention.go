@@ -0,0 +1,136 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/retention"
+	"github.com/spf13/cobra"
+)
+
+// retentionCmd represents the retention command
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Gets and extends the Object Lock retention and sets the legal hold of objects.",
+}
+
+// retentionGetCmd represents the retention get command
+var retentionGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Displays the retention and the legal hold of an object, or of the objects under a prefix.",
+	Long: `Displays the retention and the legal hold of an object, or of the objects under a prefix.
+
+Synopsis
+retention get
+<S3Uri>
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+
+Options
+path (string)
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+Each object is displayed in a line, followed by its mode, retain until date and legal hold status separated by tabs.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.`,
+	Run: retention.RunGet,
+}
+
+// retentionExtendCmd represents the retention extend command
+var retentionExtendCmd = &cobra.Command{
+	Use:   "extend",
+	Short: "Extends the retention of an object, or of the objects under a prefix.",
+	Long: `Extends the retention of an object, or of the objects under a prefix.
+The retention is never shortened. The objects that are already retained until the date are skipped.
+
+Synopsis
+retention extend
+<S3Uri>
+--retain-until-date <value>
+[--mode <value>]
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+[--dryrun]
+
+Options
+path (string)
+
+--retain-until-date (string) The date until which the objects are retained, in RFC 3339 format. e.g. 2030-01-02T03:04:05Z
+
+--mode (string) The retention mode, GOVERNANCE or COMPLIANCE. The current mode is kept if it is omitted.
+It is required for the objects without retention. COMPLIANCE mode cannot be changed to GOVERNANCE mode.
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+--dryrun (boolean) Displays the operations that would be performed using the specified command without actually running them.
+
+The objects are processed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: retention.RunExtend,
+}
+
+// retentionLegalHoldCmd represents the retention legal-hold command
+var retentionLegalHoldCmd = &cobra.Command{
+	Use:   "legal-hold",
+	Short: "Sets the legal hold of an object, or of the objects under a prefix.",
+	Long: `Sets the legal hold of an object, or of the objects under a prefix.
+
+Synopsis
+retention legal-hold
+<S3Uri>
+--status <value>
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+[--dryrun]
+
+Options
+path (string)
+
+--status (string) The legal hold status, ON or OFF.
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+--dryrun (boolean) Displays the operations that would be performed using the specified command without actually running them.
+
+The objects are processed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: retention.RunLegalHold,
+}
+
+func init() {
+	rootCmd.AddCommand(retentionCmd)
+	retentionCmd.AddCommand(retentionGetCmd)
+	retentionCmd.AddCommand(retentionExtendCmd)
+	retentionCmd.AddCommand(retentionLegalHoldCmd)
+	retention.InitGet(retentionGetCmd)
+	retention.InitExtend(retentionExtendCmd)
+	retention.InitLegalHold(retentionLegalHoldCmd)
+}
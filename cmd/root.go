@@ -23,14 +23,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var cfgErr error
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -40,6 +44,19 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindFlags(cmd); err != nil {
+			return err
+		}
+		if config.Debug() {
+			if cfgErr != nil {
+				fmt.Fprintln(os.Stderr, "Failed to read config file:", cfgErr)
+			} else {
+				fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+			}
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -71,7 +88,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
@@ -80,10 +97,85 @@ func initConfig() {
 		viper.SetConfigName(".s3cli-mini")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match.
+	// e.g. S3CLI_REGION for --region, S3CLI_CP_CONTENT_TYPE for --content-type of cp.
+	viper.SetEnvPrefix(config.EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	cfgErr = viper.ReadInConfig()
+}
+
+// bindFlags sets the values from the config file and the environment values
+// to the flags that are not set on the command line.
+// The global flags are bound to the top level keys, e.g. "region",
+// and the flags of the subcommands are bound to the keys prefixed by the command path, e.g. "cp.content-type".
+// The persistent flags of the subcommands are prefixed by the path of the command that defines them.
+func bindFlags(cmd *cobra.Command) error {
+	var err error
+	setFlag := func(key string, f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "config" || f.Name == "help" {
+			return
+		}
+		if !viper.IsSet(key) {
+			return
+		}
+		switch f.Value.Type() {
+		case "stringArray", "stringSlice":
+			for _, v := range viper.GetStringSlice(key) {
+				if err = f.Value.Set(v); err != nil {
+					break
+				}
+			}
+		case "stringToString":
+			m := viper.GetStringMapString(key)
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if err = f.Value.Set(k + "=" + m[k]); err != nil {
+					break
+				}
+			}
+		default:
+			err = f.Value.Set(viper.GetString(key))
+		}
+		if err != nil {
+			err = fmt.Errorf("invalid value for %q in config: %w", key, err)
+			return
+		}
+		f.Changed = true
+	}
+
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		setFlag(flagKey(persistentFlagOwner(cmd, f), f), f)
+	})
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		setFlag(flagKey(cmd, f), f)
+	})
+	return err
+}
+
+// flagKey returns the config key of the flag of cmd.
+func flagKey(cmd *cobra.Command, f *pflag.Flag) string {
+	prefix := strings.Join(strings.Fields(cmd.CommandPath())[1:], ".")
+	if prefix == "" {
+		// the global flags of the root command
+		return f.Name
+	}
+	return prefix + "." + f.Name
+}
+
+// persistentFlagOwner returns the ancestor of cmd that defines the inherited flag,
+// so that the persistent flags of a subcommand are bound under its path, not to the top level keys.
+func persistentFlagOwner(cmd *cobra.Command, f *pflag.Flag) *cobra.Command {
+	for p := cmd.Parent(); p != nil; p = p.Parent() {
+		if p.PersistentFlags().Lookup(f.Name) == f {
+			return p
+		}
 	}
+	return cmd.Root()
 }
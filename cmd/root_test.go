@@ -0,0 +1,128 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shogo82148/s3cli-mini/cmd/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestBindFlags_Precedence(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { cfgFile = "" })
+
+	cfgFile = filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(cfgFile, []byte(`
+s3:
+  addressing_style: virtual
+profiles:
+  foo:
+    s3:
+      addressing_style: path
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		env  string // S3CLI_S3_ADDRESSING_STYLE
+		want string
+	}{
+		{"s3", nil, "", "virtual"},
+		{"profile", []string{"--profile", "foo"}, "", "path"},
+		{"env", []string{"--profile", "foo"}, "auto", "auto"},
+		{"flag", []string{"--profile", "foo", "--addressing-style", "virtual"}, "auto", "virtual"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_PROFILE", "")
+			t.Setenv("S3CLI_S3_ADDRESSING_STYLE", tt.env)
+			viper.Reset()
+			initConfig()
+
+			root := &cobra.Command{
+				Use: "s3cli-mini",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+					return bindFlags(cmd)
+				},
+			}
+			config.InitFlag(root)
+			root.AddCommand(&cobra.Command{
+				Use: "ls",
+				Run: func(cmd *cobra.Command, args []string) {},
+			})
+			root.SetArgs(append([]string{"ls"}, tt.args...))
+			if err := root.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if got := config.AddressingStyle(); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBindFlags_SubcommandPersistentFlag(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { cfgFile = "" })
+
+	// "output" is both a top level key and a persistent flag of a subcommand.
+	cfgFile = filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(cfgFile, []byte(`
+output: top
+parent:
+  output: parent
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	initConfig()
+
+	root := &cobra.Command{
+		Use: "s3cli-mini",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindFlags(cmd)
+		},
+	}
+	parent := &cobra.Command{Use: "parent"}
+	var output string
+	parent.PersistentFlags().StringVar(&output, "output", "", "")
+	parent.AddCommand(&cobra.Command{
+		Use: "child",
+		Run: func(cmd *cobra.Command, args []string) {},
+	})
+	root.AddCommand(parent)
+	root.SetArgs([]string{"parent", "child"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if output != "parent" {
+		t.Errorf("want %q, got %q", "parent", output)
+	}
+}
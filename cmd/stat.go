@@ -0,0 +1,52 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/stat"
+	"github.com/spf13/cobra"
+)
+
+// statCmd represents the stat command
+var statCmd = &cobra.Command{
+	Use:   "stat",
+	Short: "Displays the headers, the metadata and the tags of an S3 object.",
+	Long: `Displays the headers, the metadata and the tags of an S3 object.
+
+Synopsis
+stat
+<S3Uri>
+[--version-id <value>]
+
+Options
+path (string)
+
+--version-id (string) The version of the object.
+
+The size, the last modified time, the ETag, the storage class, the server-side encryption,
+the checksums, the user metadata and the tags of the object are displayed.`,
+	Run: stat.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(statCmd)
+	stat.Init(statCmd)
+}
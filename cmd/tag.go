@@ -0,0 +1,136 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/tag"
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Gets, sets and deletes the tags of objects.",
+}
+
+// tagGetCmd represents the tag get command
+var tagGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Displays the tags of an object, or of the objects under a prefix.",
+	Long: `Displays the tags of an object, or of the objects under a prefix.
+
+Synopsis
+tag get
+<S3Uri>
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+
+Options
+path (string)
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+Each object is displayed in a line, followed by its tags separated by a tab.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.`,
+	Run: tag.RunGet,
+}
+
+// tagSetCmd represents the tag set command
+var tagSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the tags of an object, or of the objects under a prefix.",
+	Long: `Sets the tags of an object, or of the objects under a prefix.
+
+Synopsis
+tag set
+<S3Uri>
+--tags <value>
+[--merge | --replace]
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+[--dryrun]
+
+Options
+path (string)
+
+--tags (map) The tags to set. e.g. --tags Key1=Value1,Key2=Value2
+
+--merge (boolean) Merges the tags into the existing tags. The tags with the same keys are overwritten. This is the default.
+
+--replace (boolean) Replaces the existing tags with the tags.
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+--dryrun (boolean) Displays the operations that would be performed using the specified command without actually running them.
+
+The objects are tagged in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: tag.RunSet,
+}
+
+// tagDeleteCmd represents the tag delete command
+var tagDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes the tags of an object, or of the objects under a prefix.",
+	Long: `Deletes the tags of an object, or of the objects under a prefix.
+
+Synopsis
+tag delete
+<S3Uri>
+[--keys <value>]
+[--recursive]
+[--include <value>]
+[--exclude <value>]
+[--dryrun]
+
+Options
+path (string)
+
+--keys (list) The keys of the tags to delete. All tags are deleted if it is omitted.
+
+--recursive (boolean) Command is performed on all objects under the specified prefix.
+
+--include (string) Don't exclude objects in the command that match the specified pattern.
+
+--exclude (string) Exclude all objects from the command that matches the specified pattern.
+
+--dryrun (boolean) Displays the operations that would be performed using the specified command without actually running them.
+
+The objects are processed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: tag.RunDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagGetCmd)
+	tagCmd.AddCommand(tagSetCmd)
+	tagCmd.AddCommand(tagDeleteCmd)
+	tag.InitGet(tagGetCmd)
+	tag.InitSet(tagSetCmd)
+	tag.InitDelete(tagDeleteCmd)
+}
@@ -0,0 +1,60 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/tree"
+	"github.com/spf13/cobra"
+)
+
+// treeCmd represents the tree command
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Displays the prefixes and the objects under a prefix as a tree.",
+	Long: `Displays the prefixes and the objects under a prefix as a tree.
+
+Synopsis
+tree
+<S3Uri>
+[-L <value>]
+[--dirs-only]
+[--size]
+[--human-readable]
+
+Options
+path (string)
+
+-L, --level (integer) Descends only N levels of the prefixes. The default is 0, no limit.
+
+-d, --dirs-only (boolean) Displays the prefixes only.
+
+-s, --size (boolean) Displays the sizes of the objects.
+
+--human-readable (boolean) Displays sizes in human readable format.
+
+The prefixes in the same level are listed in parallel up to max_concurrent_requests of the s3 settings.`,
+	Run: tree.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(treeCmd)
+	tree.Init(treeCmd)
+}
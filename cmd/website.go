@@ -0,0 +1,54 @@
+// Copyright © 2019 Shogo Ichinose
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/shogo82148/s3cli-mini/cmd/internal/website"
+	"github.com/spf13/cobra"
+)
+
+// websiteCmd represents the website command
+var websiteCmd = &cobra.Command{
+	Use:   "website",
+	Short: "Set the website configuration for a bucket.",
+	Long: `Set the website configuration for a bucket.
+
+Synopsis
+website
+<S3Uri>
+--index-document <value>
+[--error-document <value>]
+
+Options
+path (string) s3://bucket
+
+--index-document (string) A suffix that is appended to a request that is for a directory on the website endpoint (e.g. if the suffix is index.html and you make a request to samplebucket/images/ the data that is returned will be for the object with the key name images/index.html). The suffix must not be empty and must not include a slash character.
+
+--error-document (string) The object key name to use when a 4XX class error occurs.
+
+To publish the redirects of the website, use cp --recursive --website-redirects <file>.`,
+	Run: website.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(websiteCmd)
+	website.Init(websiteCmd)
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/shogo82148/s3cli-mini/internal/fastwalk"
+	"github.com/shogo82148/s3cli-mini/internal/testtree"
 )
 
 func formatFileModes(m map[string]os.FileMode) string {
@@ -39,21 +40,7 @@ func testFastWalk(t *testing.T, files map[string]string, callback func(path stri
 		t.Fatal(err)
 	}
 	defer os.RemoveAll(tempdir)
-	for path, contents := range files {
-		file := filepath.Join(tempdir, "/src", path)
-		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
-			t.Fatal(err)
-		}
-		var err error
-		if after, ok := strings.CutPrefix(contents, "LINK:"); ok {
-			err = os.Symlink(after, file)
-		} else {
-			err = os.WriteFile(file, []byte(contents), 0644)
-		}
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
+	testtree.Write(t, filepath.Join(tempdir, "src"), files)
 	got := map[string]os.FileMode{}
 	var mu sync.Mutex
 	if err := fastwalk.Walk(tempdir, func(path string, typ os.FileMode) error {
@@ -119,11 +106,7 @@ func TestFastWalk_Symlink(t *testing.T) {
 	case "windows", "plan9":
 		t.Skipf("skipping on %s", runtime.GOOS)
 	}
-	testFastWalk(t, map[string]string{
-		"foo/foo.go": "one",
-		"bar/bar.go": "LINK:../foo.go",
-		"symdir":     "LINK:foo",
-	},
+	testFastWalk(t, testtree.Symlinks(),
 		func(path string, typ os.FileMode) error {
 			return nil
 		},
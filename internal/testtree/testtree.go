@@ -0,0 +1,41 @@
+// Package testtree writes the file trees for tests.
+package testtree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Write writes the files under root.
+// The keys of files are the paths separated by slashes,
+// and the values are the contents.
+// The contents with "LINK:" prefix are the targets of symbolic links.
+func Write(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for path, contents := range files {
+		file := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if after, ok := strings.CutPrefix(contents, "LINK:"); ok {
+			err = os.Symlink(after, file)
+		} else {
+			err = os.WriteFile(file, []byte(contents), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Symlinks returns the files with a broken link to a file and a link to a directory.
+func Symlinks() map[string]string {
+	return map[string]string{
+		"foo/foo.go": "one",
+		"bar/bar.go": "LINK:../foo.go",
+		"symdir":     "LINK:foo",
+	}
+}
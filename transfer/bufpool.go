@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+)
+
+// the granularity of the size classes for large buffers (1MiB).
+const bufferClassGranularity = 1024 * 1024
+
+// bufferPool is a pool of byte slices grouped by size classes.
+// The total size of the buffers, including the idle ones in the pool, never exceeds the limit.
+// If the limit is reached, get waits for other buffers to be put back.
+type bufferPool struct {
+	mu       sync.Mutex
+	limit    int64
+	used     int64            // the total size of the buffers in use
+	idle     int64            // the total size of the buffers in the pool
+	free     map[int][][]byte // size class -> idle buffers
+	released chan struct{}    // closed when a buffer is put back
+}
+
+func newBufferPool(limit int64) *bufferPool {
+	return &bufferPool{
+		limit:    limit,
+		free:     make(map[int][][]byte),
+		released: make(chan struct{}),
+	}
+}
+
+// sizeClass returns the capacity of the buffers for the size.
+func sizeClass(size int) int {
+	if size >= bufferClassGranularity {
+		return (size + bufferClassGranularity - 1) / bufferClassGranularity * bufferClassGranularity
+	}
+	class := 1024
+	for class < size {
+		class <<= 1
+	}
+	return class
+}
+
+// get returns a buffer of length size.
+// The caller must return the buffer by calling put.
+func (p *bufferPool) get(ctx context.Context, size int) ([]byte, error) {
+	class := sizeClass(size)
+	for {
+		p.mu.Lock()
+		if bufs := p.free[class]; len(bufs) > 0 {
+			buf := bufs[len(bufs)-1]
+			p.free[class] = bufs[:len(bufs)-1]
+			p.idle -= int64(class)
+			p.used += int64(class)
+			p.mu.Unlock()
+			return buf[:size], nil
+		}
+
+		// release the idle buffers of other size classes to make room.
+		for c, bufs := range p.free {
+			if p.used+p.idle+int64(class) <= p.limit {
+				break
+			}
+			p.idle -= int64(c) * int64(len(bufs))
+			delete(p.free, c)
+		}
+
+		// a buffer is always available when nothing is in use,
+		// even if it is larger than the limit. otherwise, get would wait forever.
+		if p.used == 0 || p.used+p.idle+int64(class) <= p.limit {
+			p.used += int64(class)
+			p.mu.Unlock()
+			return make([]byte, size, class), nil
+		}
+
+		released := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// put returns the buffer to the pool.
+func (p *bufferPool) put(buf []byte) {
+	if buf == nil {
+		return
+	}
+	class := cap(buf)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.used -= int64(class)
+	p.idle += int64(class)
+	p.free[class] = append(p.free[class], buf[:0])
+
+	// wake up the waiters.
+	close(p.released)
+	p.released = make(chan struct{})
+}
@@ -0,0 +1,142 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSizeClass(t *testing.T) {
+	cases := []struct {
+		in  int
+		out int
+	}{
+		{0, 1024},
+		{1000, 1024},
+		{1025, 2048},
+		{1024 * 1024, 1024 * 1024},
+		{5 * 1024 * 1024, 5 * 1024 * 1024},
+		{5*1024*1024 + 1, 6 * 1024 * 1024},
+	}
+	for _, tt := range cases {
+		if got := sizeClass(tt.in); got != tt.out {
+			t.Errorf("%d: want %d, got %d", tt.in, tt.out, got)
+		}
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	ctx := t.Context()
+	const size = 1024 * 1024
+	pool := newBufferPool(2 * size)
+
+	buf1, err := pool.get(ctx, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf2, err := pool.get(ctx, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the limit is reached.
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.get(ctxTimeout, size); err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	// the buffer is reused.
+	pool.put(buf1)
+	buf3, err := pool.get(ctx, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &buf3[0] != &buf1[0] {
+		t.Error("the buffer is not reused")
+	}
+
+	// the idle buffers are released for other size classes.
+	pool.put(buf2)
+	pool.put(buf3)
+	buf4, err := pool.get(ctx, 2*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.used+pool.idle > pool.limit {
+		t.Errorf("the limit is exceeded: used %d, idle %d", pool.used, pool.idle)
+	}
+
+	// get waits for put.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.put(buf4)
+	}()
+	if _, err := pool.get(ctx, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// streamReader emulates a large non-seekable stream, such as a tarball from a pipe.
+type streamReader struct {
+	remain int64
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if r.remain <= 0 {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	n = min(n, r.remain)
+	r.remain -= n
+	clear(p[:n])
+	return int(n), nil
+}
+
+const benchmarkStreamSize = 256 * 1024 * 1024
+const benchmarkChunkSize = 5 * 1024 * 1024
+
+func BenchmarkReadChunks_BytesBuffer(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(benchmarkStreamSize)
+	for b.Loop() {
+		r := &streamReader{remain: benchmarkStreamSize}
+		for {
+			var buf bytes.Buffer
+			n, _ := buf.ReadFrom(&io.LimitedReader{R: r, N: benchmarkChunkSize})
+			if n == 0 {
+				break
+			}
+			if _, err := io.Copy(io.Discard, bytes.NewReader(buf.Bytes())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkReadChunks_BufferPool(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(benchmarkStreamSize)
+	pool := newBufferPool(4 * benchmarkChunkSize)
+	ctx := b.Context()
+	for b.Loop() {
+		r := &streamReader{remain: benchmarkStreamSize}
+		for {
+			buf, err := pool.get(ctx, benchmarkChunkSize)
+			if err != nil {
+				b.Fatal(err)
+			}
+			n, _ := io.ReadFull(r, buf)
+			if n == 0 {
+				pool.put(buf)
+				break
+			}
+			if _, err := io.Copy(io.Discard, bytes.NewReader(buf[:n])); err != nil {
+				b.Fatal(err)
+			}
+			pool.put(buf)
+		}
+	}
+}
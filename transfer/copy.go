@@ -0,0 +1,268 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Copy copies s3://srcBucket/srcKey to s3://dstBucket/dstKey.
+func (c *Client) Copy(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	j := c.newJob(ctx)
+	if c.options.DryRun {
+		j.skip(Event{
+			Operation:   OperationCopy,
+			Source:      s3URI(srcBucket, srcKey),
+			Destination: s3URI(dstBucket, dstKey),
+			Size:        -1,
+		}, SkipReasonDryRun)
+		return nil
+	}
+	return j.schedule(func(s *scheduler) error {
+		s.addFile(func() {
+			j.copy(srcBucket, srcKey, dstBucket, dstKey)
+		})
+		return nil
+	})
+}
+
+// CopyPrefix copies the objects under s3://srcBucket/srcPrefix to s3://dstBucket/dstPrefix.
+func (c *Client) CopyPrefix(ctx context.Context, srcBucket, srcPrefix, dstBucket, dstPrefix string) error {
+	if srcPrefix != "" && !strings.HasSuffix(srcPrefix, "/") {
+		srcPrefix += "/"
+	}
+
+	j := c.newJob(ctx)
+	return j.schedule(func(s *scheduler) error {
+		// walk s3
+		p := s3.NewListObjectsV2Paginator(c.s3, &s3.ListObjectsV2Input{
+			Bucket: aws.String(srcBucket),
+			Prefix: aws.String(srcPrefix),
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(j.ctx)
+			if err != nil {
+				return err
+			}
+			for _, obj := range page.Contents {
+				key := aws.ToString(obj.Key)
+				dstKey := path.Join(dstPrefix, strings.TrimPrefix(key, srcPrefix))
+				if c.options.DryRun {
+					j.skip(Event{
+						Operation:   OperationCopy,
+						Source:      s3URI(srcBucket, key),
+						Destination: s3URI(dstBucket, dstKey),
+						Size:        aws.ToInt64(obj.Size),
+					}, SkipReasonDryRun)
+					continue
+				}
+				s.addFile(func() {
+					j.copy(srcBucket, key, dstBucket, dstKey)
+				})
+			}
+		}
+		return nil
+	})
+}
+
+// copy copies the object in the scheduler.
+func (j *job) copy(srcBucket, srcKey, dstBucket, dstKey string) {
+	if !j.sched.acquire() {
+		return
+	}
+	cp := &copier{
+		job: j,
+		event: Event{
+			Operation:   OperationCopy,
+			Source:      s3URI(srcBucket, srcKey),
+			Destination: s3URI(dstBucket, dstKey),
+			Size:        -1,
+		},
+		srcBucket: srcBucket,
+		srcKey:    srcKey,
+		dstBucket: dstBucket,
+		dstKey:    dstKey,
+	}
+	cp.copy()
+}
+
+type copier struct {
+	*job
+	event             Event
+	srcBucket, srcKey string
+	dstBucket, dstKey string
+	totalSize         int64
+
+	mu    sync.Mutex
+	parts completedParts
+}
+
+// copy runs in a file worker of the scheduler, and submits the parts to the scheduler.
+// It releases the slot of the scheduler after the object is copied.
+func (c *copier) copy() {
+	if err := c.initSize(); err != nil {
+		c.sched.release()
+		c.fail(c.event, err)
+		return
+	}
+	c.event.Size = c.totalSize
+	c.emitEvent(EventStarted, 0)
+	if c.totalSize <= c.options.CopyThreshold {
+		// use CopyObject API for small size object
+		// https://docs.aws.amazon.com/AmazonS3/latest/dev/CopyingObjectsUsingAPIs.html
+		c.sched.transfer(false, c.singlePartCopy)
+		return
+	}
+
+	// multipart copy
+	// https://docs.aws.amazon.com/AmazonS3/latest/dev/CopyingObjctsMPUapi.html
+	resp, err := c.s3.CreateMultipartUpload(c.ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    aws.String(c.dstBucket),
+		Key:                       aws.String(c.dstKey),
+		ObjectLockMode:            c.options.ObjectLockMode,
+		ObjectLockRetainUntilDate: c.options.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: c.options.ObjectLockLegalHoldStatus,
+	})
+	if err != nil {
+		c.sched.release()
+		c.fail(c.event, err)
+		return
+	}
+	uploadID := aws.ToString(resp.UploadId)
+
+	c.sched.feed(func() {
+		size := c.totalSize
+		chunkSize := c.options.MultipartChunkSize
+		var wg sync.WaitGroup
+		for i, pos := int32(1), int64(0); pos < size; i, pos = i+1, pos+chunkSize {
+			if c.ctx.Err() != nil {
+				break
+			}
+			lastByte := min(pos+chunkSize, size) - 1
+			wg.Add(1)
+			c.sched.transfer(true, func() {
+				defer wg.Done()
+				if c.ctx.Err() != nil {
+					return
+				}
+				c.copyChunk(uploadID, i, pos, lastByte)
+			})
+		}
+
+		c.sched.complete(func() {
+			defer c.sched.release()
+			wg.Wait()
+			c.completeCopy(uploadID)
+		})
+	})
+}
+
+func (c *copier) completeCopy(uploadID string) {
+	if c.ctx.Err() != nil {
+		// the request is aborted. clean up temporary resources.
+		c.abortCopy(uploadID)
+		return
+	}
+	sort.Sort(c.parts)
+	_, err := c.s3.CompleteMultipartUpload(c.ctxAbort, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(c.dstBucket),
+		Key:      aws.String(c.dstKey),
+		UploadId: aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: c.parts,
+		},
+		IfMatch:     c.options.ifMatch(),
+		IfNoneMatch: c.options.ifNoneMatch(),
+	})
+	if isPreconditionFailed(err) {
+		// the copy is skipped, and the others go on. clean up the parts.
+		c.abortCopy(uploadID)
+		c.skip(c.event, SkipReasonPreconditionFailed)
+		return
+	}
+	if err != nil {
+		c.fail(c.event, err)
+		return
+	}
+	c.emitEvent(EventCompleted, 0)
+}
+
+func (c *copier) abortCopy(uploadID string) {
+	_, err := c.s3.AbortMultipartUpload(c.ctxAbort, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(c.dstBucket),
+		Key:      aws.String(c.dstKey),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		c.abortError(fmt.Errorf("failed to abort multipart upload of %s: %w", c.event.Destination, err))
+	}
+}
+
+func (c *copier) initSize() error {
+	resp, err := c.s3.HeadObject(c.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.srcBucket),
+		Key:    aws.String(c.srcKey),
+	})
+	if err != nil {
+		return err
+	}
+	c.totalSize = aws.ToInt64(resp.ContentLength)
+	return nil
+}
+
+func (c *copier) singlePartCopy() {
+	defer c.sched.release()
+	if c.ctx.Err() != nil {
+		return
+	}
+	_, err := c.s3.CopyObject(c.ctx, &s3.CopyObjectInput{
+		Bucket:                    aws.String(c.dstBucket),
+		Key:                       aws.String(c.dstKey),
+		CopySource:                aws.String(c.srcBucket + "/" + c.srcKey),
+		ObjectLockMode:            c.options.ObjectLockMode,
+		ObjectLockRetainUntilDate: c.options.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: c.options.ObjectLockLegalHoldStatus,
+		IfMatch:                   c.options.ifMatch(),
+		IfNoneMatch:               c.options.ifNoneMatch(),
+	})
+	if err != nil {
+		c.failOrSkip(c.event, err)
+		return
+	}
+	c.emitEvent(EventProgress, c.totalSize)
+	c.emitEvent(EventCompleted, 0)
+}
+
+func (c *copier) copyChunk(uploadID string, num int32, pos, lastByte int64) {
+	resp, err := c.s3.UploadPartCopy(c.ctx, &s3.UploadPartCopyInput{
+		Bucket:          aws.String(c.dstBucket),
+		Key:             aws.String(c.dstKey),
+		CopySource:      aws.String(c.srcBucket + "/" + c.srcKey),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", pos, lastByte)),
+		UploadId:        aws.String(uploadID),
+		PartNumber:      aws.Int32(num),
+	})
+	if err != nil {
+		c.fail(c.event, err)
+		return
+	}
+	part := types.CompletedPart{ETag: resp.CopyPartResult.ETag, PartNumber: aws.Int32(num)}
+	c.mu.Lock()
+	c.parts = append(c.parts, part)
+	c.mu.Unlock()
+	c.emitEvent(EventProgress, lastByte-pos+1)
+}
+
+func (c *copier) emitEvent(typ EventType, bytes int64) {
+	e := c.event
+	e.Type = typ
+	e.Bytes = bytes
+	c.emit(e)
+}
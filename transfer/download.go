@@ -0,0 +1,402 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// the number of retries for reading the body of a part.
+const partBodyMaxRetries = 3
+
+// Download downloads s3://bucket/key to the stream w.
+// The parts of a large object are downloaded in parallel, and written to w in order.
+func (c *Client) Download(ctx context.Context, bucket, key string, w io.Writer) error {
+	j := c.newJob(ctx)
+	defer j.cancel()
+	e := Event{
+		Operation:   OperationDownload,
+		Source:      s3URI(bucket, key),
+		Destination: StreamLocation,
+		Size:        -1,
+	}
+	if c.options.DryRun {
+		j.skip(e, SkipReasonDryRun)
+		return nil
+	}
+	if err := j.download(e, bucket, key, w); err != nil {
+		j.fail(e, err)
+		return err
+	}
+	return nil
+}
+
+func (j *job) download(e Event, bucket, key string, w io.Writer) error {
+	head, err := j.s3.HeadObject(j.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	e.Size = aws.ToInt64(head.ContentLength)
+	e.Type = EventStarted
+	j.emit(e)
+
+	if e.Size <= j.options.DownloadPartSize {
+		// small object. download it with a single request.
+		res, err := j.s3.GetObject(j.ctx, &s3.GetObjectInput{
+			Bucket:  aws.String(bucket),
+			Key:     aws.String(key),
+			IfMatch: head.ETag,
+		})
+		if err != nil {
+			return err
+		}
+		body := res.Body
+		defer body.Close()
+		n, err := io.Copy(w, body)
+		if err != nil {
+			return err
+		}
+		e.Type, e.Bytes = EventProgress, n
+		j.emit(e)
+	} else {
+		d := &streamDownloader{
+			s3:          j.s3,
+			throttle:    j.throttle,
+			bucket:      bucket,
+			key:         key,
+			size:        e.Size,
+			etag:        head.ETag,
+			partSize:    j.options.DownloadPartSize,
+			concurrency: j.options.DownloadConcurrency,
+			maxMemory:   j.options.MaxMemory,
+			progress: func(n int64) {
+				e.Type, e.Bytes = EventProgress, n
+				j.emit(e)
+			},
+		}
+		if err := d.download(j.ctx, w); err != nil {
+			return err
+		}
+	}
+	e.Type, e.Bytes = EventCompleted, 0
+	j.emit(e)
+	return nil
+}
+
+// DownloadFile downloads s3://bucket/key to the local file.
+func (c *Client) DownloadFile(ctx context.Context, bucket, key, name string) error {
+	j := c.newJob(ctx)
+	j.root = filepath.Dir(name)
+	if c.options.DryRun {
+		j.skip(Event{
+			Operation:   OperationDownload,
+			Source:      s3URI(bucket, key),
+			Destination: name,
+			Size:        -1,
+		}, SkipReasonDryRun)
+		return nil
+	}
+	err := j.schedule(func(s *scheduler) error {
+		s.addFile(func() {
+			j.downloadFile(bucket, key, name, -1)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return j.createSymlinks()
+}
+
+// DownloadDir downloads the objects under s3://bucket/prefix to the local directory.
+// With SymlinkPreserve, the symbolic links are created after all the objects are downloaded.
+func (c *Client) DownloadDir(ctx context.Context, bucket, prefix, dir string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	j := c.newJob(ctx)
+	j.root = dir
+	err := j.schedule(func(s *scheduler) error {
+		// walk s3
+		p := s3.NewListObjectsV2Paginator(c.s3, &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(j.ctx)
+			if err != nil {
+				return err
+			}
+			for _, obj := range page.Contents {
+				key := aws.ToString(obj.Key)
+				name := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(key, prefix)))
+				size := aws.ToInt64(obj.Size)
+				if c.options.DryRun {
+					j.skip(Event{
+						Operation:   OperationDownload,
+						Source:      s3URI(bucket, key),
+						Destination: name,
+						Size:        size,
+					}, SkipReasonDryRun)
+					continue
+				}
+				s.addFile(func() {
+					j.downloadFile(bucket, key, name, size)
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return j.createSymlinks()
+}
+
+// downloadFile downloads the object to the local file in the scheduler.
+func (j *job) downloadFile(bucket, key, name string, size int64) {
+	e := Event{
+		Operation:   OperationDownload,
+		Source:      s3URI(bucket, key),
+		Destination: name,
+		Size:        size,
+	}
+	if j.ctx.Err() != nil {
+		return
+	}
+	if j.options.Symlinks == SymlinkPreserve {
+		// check the object before opening the file,
+		// because opening the link of the previous download writes through it.
+		target, ok, err := j.symlinkTarget(bucket, key, size)
+		if err != nil {
+			j.fail(e, err)
+			return
+		}
+		if ok {
+			j.addSymlink(e, name, target)
+			return
+		}
+		// don't write through the links to outside of the destination,
+		// that the previous download may have created.
+		err = j.checkInsideRoot(filepath.Dir(name))
+		if errors.Is(err, errSymlinkEscape) {
+			j.skip(e, SkipReasonSymlinkEscape)
+			return
+		}
+		if err != nil {
+			j.fail(e, err)
+			return
+		}
+		if err := removeSymlink(name); err != nil {
+			j.fail(e, err)
+			return
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		j.fail(e, err)
+		return
+	}
+	f, err := j.sched.openFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		j.fail(e, err)
+		return
+	}
+	j.sched.transfer(false, func() {
+		if j.ctx.Err() != nil {
+			f.Close()
+			return
+		}
+		e.Type = EventStarted
+		j.emit(e)
+		out, err := j.downloader.DownloadObject(j.ctx, &transfermanager.DownloadObjectInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			WriterAt: f,
+		})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			j.fail(e, err)
+			return
+		}
+		if j.options.Preserve {
+			if err := restoreFileAttrs(name, out.Metadata, aws.ToTime(out.LastModified)); err != nil {
+				j.fail(e, err)
+				return
+			}
+		}
+		e.Size = aws.ToInt64(out.ContentLength)
+		e.Type, e.Bytes = EventProgress, e.Size
+		j.emit(e)
+		e.Type, e.Bytes = EventCompleted, 0
+		j.emit(e)
+	})
+}
+
+type objectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// streamDownloader downloads an object with parallel ranged GETs,
+// and writes the parts in order to a non-seekable writer, such as STDOUT.
+// The memory usage is bounded by maxMemory bytes.
+// If partSize is larger than maxMemory, the parts are downloaded in the size of maxMemory.
+type streamDownloader struct {
+	s3          objectGetter
+	throttle    *throttler
+	bucket      string
+	key         string
+	size        int64
+	etag        *string
+	partSize    int64
+	concurrency int
+	maxMemory   int64
+
+	// progress is called with the size of each part written, if it is not nil.
+	progress func(n int64)
+}
+
+type downloadedPart struct {
+	buf []byte
+	err error
+}
+
+func (d *streamDownloader) download(ctx context.Context, w io.Writer) error {
+	// wait for all goroutines after cancellation.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	partSize := d.partSize
+	if partSize <= 0 {
+		partSize = DefaultDownloadPartSize
+	}
+	concurrency := d.concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDownloadConcurrency
+	}
+	maxMemory := d.maxMemory
+	if maxMemory <= 0 {
+		maxMemory = DefaultMaxMemory
+	}
+	// a part must fit in the memory budget.
+	partSize = min(partSize, maxMemory)
+
+	// the buffers limit the number of parts in memory.
+	// a buffer is taken before fetching a part, and is returned after the part is written.
+	numBuffers := maxMemory / partSize
+	numParts := (d.size + partSize - 1) / partSize
+	numBuffers = min(numBuffers, numParts)
+	buffers := make(chan []byte, numBuffers)
+	for range numBuffers {
+		buffers <- nil // allocated lazily
+	}
+
+	// the parts are sent to the writer in order.
+	results := make(chan chan downloadedPart, numBuffers)
+	sem := make(chan struct{}, concurrency)
+
+	wg.Go(func() {
+		defer close(results)
+		for i := range numParts {
+			var buf []byte
+			select {
+			case buf = <-buffers:
+			case <-ctx.Done():
+				return
+			}
+			if d.throttle != nil {
+				if err := d.throttle.wait(ctx); err != nil {
+					return
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			start := i * partSize
+			end := min(start+partSize, d.size)
+			if int64(cap(buf)) < partSize {
+				buf = make([]byte, partSize)
+			}
+			buf = buf[:end-start]
+
+			ch := make(chan downloadedPart, 1)
+			results <- ch
+			wg.Go(func() {
+				defer func() { <-sem }()
+				err := d.fetch(ctx, buf, start, end)
+				ch <- downloadedPart{buf: buf, err: err}
+			})
+		}
+	})
+
+	for ch := range results {
+		var part downloadedPart
+		select {
+		case part = <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if part.err != nil {
+			return part.err
+		}
+		if _, err := w.Write(part.buf); err != nil {
+			return err
+		}
+		if d.progress != nil {
+			d.progress(int64(len(part.buf)))
+		}
+		buffers <- part.buf
+	}
+	return ctx.Err()
+}
+
+// fetch downloads the range [start, end) of the object into buf.
+// It doesn't retry if the object is modified during the download.
+func (d *streamDownloader) fetch(ctx context.Context, buf []byte, start, end int64) error {
+	var err error
+	for range partBodyMaxRetries {
+		err = d.fetchOnce(ctx, buf, start, end)
+		if err == nil || ctx.Err() != nil || isPreconditionFailed(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (d *streamDownloader) fetchOnce(ctx context.Context, buf []byte, start, end int64) error {
+	resp, err := d.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(d.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end-1)),
+
+		// make sure that all parts are from the same object.
+		IfMatch: d.etag,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return fmt.Errorf("failed to read bytes %d-%d of s3://%s/%s: %w", start, end-1, d.bucket, d.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+type fakeObjectGetter struct {
+	data []byte
+	etag string
+
+	mu        sync.Mutex
+	inflight  int
+	maxFlight int
+	maxRange  int
+	calls     int
+}
+
+func (f *fakeObjectGetter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if aws.ToString(params.IfMatch) != f.etag {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	var start, end int
+	if _, err := fmt.Sscanf(aws.ToString(params.Range), "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.inflight++
+	f.maxFlight = max(f.maxFlight, f.inflight)
+	f.maxRange = max(f.maxRange, end-start+1)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inflight--
+		f.mu.Unlock()
+	}()
+
+	// simulate the latency, so the parts are completed out of order.
+	time.Sleep(time.Duration(rand.IntN(5)) * time.Millisecond)
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(f.data[start : end+1])),
+	}, nil
+}
+
+func TestStreamDownloader(t *testing.T) {
+	data := make([]byte, 1000003)
+	for i := range data {
+		data[i] = byte(rand.IntN(256))
+	}
+	svc := &fakeObjectGetter{data: data, etag: `"etag"`}
+
+	d := &streamDownloader{
+		s3:          svc,
+		bucket:      "bucket",
+		key:         "key",
+		size:        int64(len(data)),
+		etag:        aws.String(`"etag"`),
+		partSize:    10000,
+		concurrency: 8,
+		maxMemory:   40000,
+	}
+	var buf bytes.Buffer
+	if err := d.download(t.Context(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("the downloaded data is broken")
+	}
+
+	// the memory budget (4 parts) limits the concurrency.
+	if svc.maxFlight > 4 {
+		t.Errorf("want at most 4 concurrent requests, got %d", svc.maxFlight)
+	}
+}
+
+func TestStreamDownloader_Error(t *testing.T) {
+	svc := &fakeObjectGetter{data: make([]byte, 100000), etag: `"modified"`}
+
+	d := &streamDownloader{
+		s3:       svc,
+		bucket:   "bucket",
+		key:      "key",
+		size:     100000,
+		etag:     aws.String(`"etag"`),
+		partSize: 1000,
+	}
+	if err := d.download(t.Context(), io.Discard); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestStreamDownloader_PreconditionFailed(t *testing.T) {
+	svc := &fakeObjectGetter{data: make([]byte, 1000), etag: `"modified"`}
+
+	d := &streamDownloader{
+		s3:       svc,
+		bucket:   "bucket",
+		key:      "key",
+		size:     1000,
+		etag:     aws.String(`"etag"`),
+		partSize: 1000,
+	}
+	if err := d.download(t.Context(), io.Discard); !isPreconditionFailed(err) {
+		t.Errorf("want PreconditionFailed, got %v", err)
+	}
+
+	// the modified object is not retried.
+	if svc.calls != 1 {
+		t.Errorf("want 1 request, got %d", svc.calls)
+	}
+}
+
+func TestStreamDownloader_SmallMaxMemory(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(rand.IntN(256))
+	}
+	svc := &fakeObjectGetter{data: data, etag: `"etag"`}
+
+	d := &streamDownloader{
+		s3:        svc,
+		bucket:    "bucket",
+		key:       "key",
+		size:      int64(len(data)),
+		etag:      aws.String(`"etag"`),
+		partSize:  4000,
+		maxMemory: 1000,
+	}
+	var buf bytes.Buffer
+	if err := d.download(t.Context(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("the downloaded data is broken")
+	}
+
+	// the part size is lowered to fit in the memory budget.
+	if svc.maxRange > 1000 {
+		t.Errorf("want parts of at most 1000 bytes, got %d", svc.maxRange)
+	}
+}
@@ -0,0 +1,138 @@
+package transfer
+
+import "strings"
+
+// EventType is the type of Event.
+type EventType int
+
+const (
+	// EventStarted is sent when the transfer of a file or an object is started.
+	EventStarted EventType = iota
+
+	// EventProgress is sent when a part of the file or the object is transferred.
+	EventProgress
+
+	// EventCompleted is sent when the transfer is completed.
+	EventCompleted
+
+	// EventFailed is sent when the transfer is failed.
+	// Only the first failure is reported, because it cancels the other transfers.
+	EventFailed
+
+	// EventSkipped is sent when the transfer is skipped. Event.Reason describes the reason.
+	EventSkipped
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventStarted:
+		return "started"
+	case EventProgress:
+		return "progress"
+	case EventCompleted:
+		return "completed"
+	case EventFailed:
+		return "failed"
+	case EventSkipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// Operation is the kind of the transfer.
+type Operation int
+
+const (
+	// OperationUpload uploads a local file or a stream to S3.
+	OperationUpload Operation = iota
+
+	// OperationDownload downloads an S3 object to a local file or a stream.
+	OperationDownload
+
+	// OperationCopy copies an S3 object to another S3 object.
+	OperationCopy
+
+	// OperationRedirect creates an empty S3 object that redirects to another location.
+	OperationRedirect
+)
+
+func (op Operation) String() string {
+	switch op {
+	case OperationUpload:
+		return "upload"
+	case OperationDownload:
+		return "download"
+	case OperationCopy:
+		return "copy"
+	case OperationRedirect:
+		return "redirect"
+	}
+	return "unknown"
+}
+
+// StreamLocation is the location of the streams in Event.
+const StreamLocation = "-"
+
+// SkipReasonDryRun is the reason of EventSkipped in the dry run mode.
+const SkipReasonDryRun = "dryrun"
+
+// SkipReasonPreconditionFailed is the reason of EventSkipped when S3 rejects the conditional write,
+// i.e. the object already exists with Options.NoOverwrite, or its ETag doesn't match Options.IfMatch.
+const SkipReasonPreconditionFailed = "precondition failed"
+
+// SkipReasonBrokenSymlink is the reason of EventSkipped when the symbolic link points to nothing.
+const SkipReasonBrokenSymlink = "broken symlink"
+
+// SkipReasonSymlinkLoop is the reason of EventSkipped when following the symbolic link makes a loop.
+const SkipReasonSymlinkLoop = "symlink loop"
+
+// SkipReasonSymlinkEscape is the reason of EventSkipped when the symbolic link or the file to download
+// is outside of the destination through the symbolic links.
+const SkipReasonSymlinkEscape = "symlink outside of the destination"
+
+// Event is an event of a transfer.
+type Event struct {
+	Type      EventType
+	Operation Operation
+
+	// Source and Destination are the local paths, S3 URIs (s3://bucket/key) or StreamLocation.
+	// For OperationRedirect, Source is the S3 URI of the object, and Destination is the redirect location.
+	Source      string
+	Destination string
+
+	// Size is the size of the file or the object in bytes. It is -1 if it is unknown.
+	Size int64
+
+	// Bytes is the number of bytes transferred by the event.
+	// It is only set for EventProgress.
+	Bytes int64
+
+	// Reason is the reason of EventSkipped.
+	Reason string
+
+	// Err is the error of EventFailed.
+	Err error
+}
+
+// EventHandler handles the events of the transfers.
+// HandleEvent is called from multiple goroutines, so it must be safe for concurrent use.
+type EventHandler interface {
+	HandleEvent(e Event)
+}
+
+// EventHandlerFunc is an adapter to allow the use of ordinary functions as EventHandler.
+type EventHandlerFunc func(e Event)
+
+// HandleEvent calls f(e).
+func (f EventHandlerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+func s3URI(bucket, key string) string {
+	var b strings.Builder
+	b.WriteString("s3://")
+	b.WriteString(bucket)
+	b.WriteByte('/')
+	b.WriteString(key)
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the keys of the user metadata that Options.Preserve stores the attributes of the files in.
+const (
+	metadataMtime = "mtime"
+	metadataMode  = "mode"
+	metadataUID   = "uid"
+	metadataGID   = "gid"
+)
+
+// fileMetadata returns the user metadata that keeps the attributes of the file.
+func fileMetadata(info os.FileInfo) map[string]string {
+	m := map[string]string{
+		metadataMtime: info.ModTime().UTC().Format(time.RFC3339Nano),
+		metadataMode:  fmt.Sprintf("%04o", info.Mode().Perm()),
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		m[metadataUID] = strconv.Itoa(uid)
+		m[metadataGID] = strconv.Itoa(gid)
+	}
+	return m
+}
+
+// restoreFileAttrs restores the attributes of the downloaded file from the user metadata.
+// The modification time falls back to lastModified if the metadata doesn't have it.
+// The invalid values, e.g. written by other tools, are ignored.
+func restoreFileAttrs(name string, metadata map[string]string, lastModified time.Time) error {
+	if s, ok := metadata[metadataMode]; ok {
+		if mode, err := strconv.ParseUint(s, 8, 32); err == nil {
+			if err := os.Chmod(name, os.FileMode(mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+
+	uid, uidErr := strconv.Atoi(metadata[metadataUID])
+	gid, gidErr := strconv.Atoi(metadata[metadataGID])
+	if uidErr == nil && gidErr == nil {
+		if err := chown(name, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	mtime := lastModified
+	if s, ok := metadata[metadataMtime]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			mtime = t
+		}
+	}
+	if mtime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(name, mtime, mtime)
+}
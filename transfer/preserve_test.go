@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRestoreFileAttrs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0750); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := fileMetadata(info)
+	if got, want := metadata[metadataMtime], "2020-01-02T03:04:05.123456789Z"; got != want {
+		t.Errorf("mtime: want %q, got %q", want, got)
+	}
+	if runtime.GOOS != "windows" {
+		if got, want := metadata[metadataMode], "0750"; got != want {
+			t.Errorf("mode: want %q, got %q", want, got)
+		}
+		if metadata[metadataUID] == "" || metadata[metadataGID] == "" {
+			t.Errorf("want uid and gid, got %v", metadata)
+		}
+	}
+
+	// restore the attributes to another file.
+	dst := filepath.Join(dir, "dst")
+	if err := os.WriteFile(dst, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreFileAttrs(dst, metadata, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ModTime().Equal(mtime) {
+		t.Errorf("mtime: want %v, got %v", mtime, got.ModTime())
+	}
+	if runtime.GOOS != "windows" && got.Mode().Perm() != 0750 {
+		t.Errorf("mode: want %o, got %o", 0750, got.Mode().Perm())
+	}
+}
+
+func TestRestoreFileAttrs_LastModified(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lastModified := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	// the metadata written by other tools are ignored.
+	metadata := map[string]string{metadataMtime: "yesterday", metadataMode: "rwxr-xr-x"}
+	if err := restoreFileAttrs(name, metadata, lastModified); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ModTime().Equal(lastModified) {
+		t.Errorf("mtime: want %v, got %v", lastModified, got.ModTime())
+	}
+	if runtime.GOOS != "windows" && got.Mode().Perm() != 0644 {
+		t.Errorf("mode: want %o, got %o", 0644, got.Mode().Perm())
+	}
+}
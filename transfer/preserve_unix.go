@@ -0,0 +1,28 @@
+//go:build !windows
+
+package transfer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// chown changes the owner of the file.
+// Only root can give the files away, so the other users keep their own files silently.
+func chown(name string, uid, gid int) error {
+	err := os.Chown(name, uid, gid)
+	if errors.Is(err, fs.ErrPermission) {
+		return nil
+	}
+	return err
+}
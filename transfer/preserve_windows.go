@@ -0,0 +1,14 @@
+//go:build windows
+
+package transfer
+
+import "os"
+
+// Windows has no uid and gid.
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+func chown(name string, uid, gid int) error {
+	return nil
+}
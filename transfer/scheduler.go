@@ -0,0 +1,201 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// task is a unit of work of the scheduler.
+// A submitted task is run exactly once, even after the scheduler is canceled,
+// so that it can release its resources, e.g. buffers, open files and multipart uploads.
+// A task should check whether the scheduler is canceled before sending requests.
+type task func()
+
+// scheduler schedules the transfers of cp.
+// A file goes through the following stages, and each stage has its own queue and workers.
+//
+//   - discovery: the caller walks the source, and submits a task for each file by addFile.
+//   - file: the file workers open the files, and submit their parts by transfer.
+//     the parts of a multipart transfer are submitted from a feeder goroutine started by feed,
+//     so that the file workers can go on to the next file.
+//   - part: the part workers send the requests of the parts.
+//   - completion: the completion workers wait for the parts, and complete the multipart transfers.
+//
+// The workers only wait for the workers of the later stages, so the stages never dead lock each other.
+type scheduler struct {
+	ctx      context.Context
+	throttle *throttler
+
+	files       chan task
+	small       chan task // single part transfers
+	large       chan task // parts of multipart transfers
+	completions chan task
+
+	// slots for the files in flight. a slot is taken before opening a file,
+	// and is released after the transfer of the file is completed.
+	slots chan struct{}
+
+	fileWorkers       sync.WaitGroup
+	feeders           sync.WaitGroup
+	partWorkers       sync.WaitGroup
+	completionWorkers sync.WaitGroup
+}
+
+func newScheduler(ctx context.Context, parallel, maxOpenFiles int, throttle *throttler) *scheduler {
+	if throttle == nil {
+		throttle = newThrottler()
+	}
+	s := &scheduler{
+		ctx:         ctx,
+		throttle:    throttle,
+		files:       make(chan task),
+		small:       make(chan task, parallel),
+		large:       make(chan task, parallel),
+		completions: make(chan task, parallel),
+		slots:       make(chan struct{}, maxOpenFiles),
+	}
+	for range parallel {
+		s.fileWorkers.Go(s.runFiles)
+		s.partWorkers.Go(s.runParts)
+		s.completionWorkers.Go(s.runCompletions)
+	}
+	return s
+}
+
+func (s *scheduler) runFiles() {
+	for t := range s.files {
+		t()
+	}
+}
+
+func (s *scheduler) runParts() {
+	small, large := s.small, s.large
+	for small != nil || large != nil {
+		var t task
+		var ok bool
+
+		// select chooses one of the ready queues at random,
+		// so the small files are not starved by the parts of large files, and vice versa.
+		select {
+		case t, ok = <-small:
+			if !ok {
+				small = nil
+				continue
+			}
+		case t, ok = <-large:
+			if !ok {
+				large = nil
+				continue
+			}
+		}
+
+		// while S3 is throttling the requests, wait for the end of the back off.
+		// if the scheduler is canceled, the task just releases its resources.
+		_ = s.throttle.wait(s.ctx)
+		t()
+	}
+}
+
+func (s *scheduler) runCompletions() {
+	for t := range s.completions {
+		t()
+	}
+}
+
+// submit sends the task to the queue.
+// If the scheduler is canceled, the task is run in the caller's goroutine instead.
+func (s *scheduler) submit(queue chan<- task, t task) {
+	select {
+	case queue <- t:
+	case <-s.ctx.Done():
+		t()
+	}
+}
+
+// addFile submits the task that opens a file and starts its transfer.
+func (s *scheduler) addFile(t task) {
+	s.submit(s.files, t)
+}
+
+// transfer submits the task that sends a request.
+// large should be true for the parts of multipart transfers.
+func (s *scheduler) transfer(large bool, t task) {
+	if large {
+		s.submit(s.large, t)
+	} else {
+		s.submit(s.small, t)
+	}
+}
+
+// feed runs fn that submits the parts of a multipart transfer in a new goroutine.
+// The number of the feeders is bounded by the slots,
+// because the caller holds a slot until the transfer is completed.
+func (s *scheduler) feed(fn func()) {
+	s.feeders.Go(fn)
+}
+
+// complete submits the task that completes a multipart transfer.
+func (s *scheduler) complete(t task) {
+	s.submit(s.completions, t)
+}
+
+// acquire waits for a slot for a file in flight, and returns true if success.
+// The caller should call release after the transfer of the file is completed.
+func (s *scheduler) acquire() bool {
+	select {
+	case <-s.ctx.Done():
+		return false
+	default:
+	}
+	select {
+	case <-s.ctx.Done():
+		return false
+	case s.slots <- struct{}{}:
+		return true
+	}
+}
+
+func (s *scheduler) release() {
+	<-s.slots
+}
+
+// openFile opens the named file with a slot.
+// The slot is released when the file is closed.
+func (s *scheduler) openFile(name string, flag int, perm os.FileMode) (*scheduledFile, error) {
+	if !s.acquire() {
+		return nil, s.ctx.Err()
+	}
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		s.release()
+		return nil, err
+	}
+	return &scheduledFile{File: f, release: s.release}, nil
+}
+
+// wait waits for all the submitted tasks.
+// The caller must not submit any files after calling wait.
+func (s *scheduler) wait() {
+	close(s.files)
+	s.fileWorkers.Wait()
+	s.feeders.Wait()
+	close(s.small)
+	close(s.large)
+	s.partWorkers.Wait()
+	close(s.completions)
+	s.completionWorkers.Wait()
+}
+
+// scheduledFile is a file opened by the scheduler.
+type scheduledFile struct {
+	*os.File
+	once    sync.Once
+	release func()
+}
+
+func (f *scheduledFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(f.release)
+	return err
+}
@@ -0,0 +1,140 @@
+package transfer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitScheduler waits for the scheduler, and fails if it doesn't finish in time.
+func waitScheduler(t *testing.T, s *scheduler) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the scheduler dead locks")
+	}
+}
+
+// addMultipartFile submits a file that has parts, same as the uploader and the copier.
+func addMultipartFile(s *scheduler, parts int, runPart func(i int), completed func()) {
+	s.addFile(func() {
+		if !s.acquire() {
+			return
+		}
+		s.feed(func() {
+			var wg sync.WaitGroup
+			for i := range parts {
+				wg.Add(1)
+				s.transfer(true, func() {
+					defer wg.Done()
+					runPart(i)
+				})
+			}
+			s.complete(func() {
+				defer s.release()
+				wg.Wait()
+				completed()
+			})
+		})
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	// a single worker and a single slot must be enough to transfer many large files.
+	s := newScheduler(t.Context(), 1, 1, nil)
+
+	var parts, completed atomic.Int64
+	for range 10 {
+		addMultipartFile(s, 10, func(i int) {
+			parts.Add(1)
+		}, func() {
+			completed.Add(1)
+		})
+	}
+	waitScheduler(t, s)
+
+	if got := parts.Load(); got != 100 {
+		t.Errorf("want 100 parts, got %d", got)
+	}
+	if got := completed.Load(); got != 10 {
+		t.Errorf("want 10 files, got %d", got)
+	}
+}
+
+func TestScheduler_Fairness(t *testing.T) {
+	s := newScheduler(t.Context(), 1, 2, nil)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	// the parts of the large file wait for the small file to be submitted.
+	submitted := make(chan struct{})
+	addMultipartFile(s, 100, func(i int) {
+		if i == 0 {
+			<-submitted
+		}
+		record("large-" + strconv.Itoa(i))
+	}, func() {})
+	s.addFile(func() {
+		s.transfer(false, func() {
+			record("small")
+		})
+		close(submitted)
+	})
+	waitScheduler(t, s)
+
+	if len(order) != 101 {
+		t.Fatalf("want 101 tasks, got %d", len(order))
+	}
+	if order[len(order)-1] == "small" {
+		t.Errorf("the small file is starved by the large file: %v", order)
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	s := newScheduler(ctx, 2, 2, nil)
+
+	var parts, skipped, completed atomic.Int64
+	for i := range 10 {
+		addMultipartFile(s, 10, func(j int) {
+			if ctx.Err() != nil {
+				skipped.Add(1)
+				return
+			}
+			if i == 3 {
+				cancel()
+			}
+			parts.Add(1)
+		}, func() {
+			completed.Add(1)
+		})
+	}
+	waitScheduler(t, s)
+
+	// all submitted tasks are run, and all slots are released.
+	if got := parts.Load() + skipped.Load(); got != completed.Load()*10 {
+		t.Errorf("want %d parts, got %d", completed.Load()*10, got)
+	}
+	if skipped.Load() == 0 {
+		t.Error("want some parts to be skipped")
+	}
+	if n := len(s.slots); n != 0 {
+		t.Errorf("want no slots in use, got %d", n)
+	}
+}
@@ -0,0 +1,251 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// SymlinkMode is the handling of the symbolic links.
+type SymlinkMode int
+
+const (
+	// SymlinkFollow uploads the files that the symbolic links point to,
+	// and walks into the directories that they point to, unless it makes a loop.
+	SymlinkFollow SymlinkMode = iota
+
+	// SymlinkSkip skips the symbolic links.
+	SymlinkSkip
+
+	// SymlinkPreserve uploads the symbolic links as empty objects with the link targets in the user metadata,
+	// and recreates the links from such objects on download.
+	// The empty objects are checked with HeadObject before download.
+	SymlinkPreserve
+)
+
+func (m SymlinkMode) String() string {
+	switch m {
+	case SymlinkFollow:
+		return "follow"
+	case SymlinkSkip:
+		return "skip"
+	case SymlinkPreserve:
+		return "preserve"
+	}
+	return "unknown"
+}
+
+// metadataSymlink is the key of the user metadata that SymlinkPreserve stores the link target in.
+const metadataSymlink = "symlink-target"
+
+// isSymlinkLoop reports whether the symbolic link p points to a directory that contains p,
+// i.e. following it makes a loop.
+// The directories are compared by the device and inode numbers,
+// so the loops through other symbolic links are also detected.
+func isSymlinkLoop(p string, target os.FileInfo) (bool, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false, err
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return false, err
+		}
+		if os.SameFile(info, target) {
+			return true, nil
+		}
+		if filepath.Dir(dir) == dir {
+			return false, nil
+		}
+	}
+}
+
+// uploadSymlink uploads the symbolic link as an empty object, in the scheduler.
+func (j *job) uploadSymlink(e Event, bucket, key, target string) {
+	if !j.sched.acquire() {
+		return
+	}
+	u := &uploader{
+		job:      j,
+		event:    e,
+		body:     bytes.NewReader(nil),
+		bucket:   bucket,
+		key:      key,
+		redirect: j.options.WebsiteRedirects[key],
+		metadata: map[string]string{metadataSymlink: filepath.ToSlash(target)},
+		done:     j.sched.release,
+	}
+	u.upload()
+}
+
+// symlinkTarget returns the link target if the object is a symbolic link uploaded by SymlinkPreserve.
+// size is the size of the object, or -1 if unknown.
+func (j *job) symlinkTarget(bucket, key string, size int64) (string, bool, error) {
+	if size > 0 {
+		// the symbolic links are uploaded as empty objects.
+		return "", false, nil
+	}
+	out, err := j.s3.HeadObject(j.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", false, err
+	}
+	target, ok := out.Metadata[metadataSymlink]
+	return target, ok, nil
+}
+
+// removeSymlink removes the named file if it is a symbolic link.
+func removeSymlink(name string) error {
+	info, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return nil
+	}
+	return os.Remove(name)
+}
+
+// symlink is a symbolic link to be created after the download.
+type symlink struct {
+	event  Event
+	name   string
+	target string
+}
+
+// addSymlink reserves the symbolic link to create after the download.
+// The target is cleaned, so that the link is resolved in the same way as it is checked.
+func (j *job) addSymlink(e Event, name, target string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.symlinks = append(j.symlinks, symlink{event: e, name: name, target: filepath.Clean(filepath.FromSlash(target))})
+}
+
+// createSymlinks creates the symbolic links, replacing the existing files.
+// It runs after all the files are downloaded, so no file is written through the links
+// that come from the objects.
+// The links that point outside of the destination, or that are under the links to outside of it,
+// are skipped unless AllowSymlinkEscape is set.
+func (j *job) createSymlinks() error {
+	for _, l := range j.symlinks {
+		e := l.event
+		err := j.createSymlink(l)
+		if errors.Is(err, errSymlinkEscape) {
+			j.skip(e, SkipReasonSymlinkEscape)
+			continue
+		}
+		if err != nil {
+			e.Type, e.Err = EventFailed, err
+			j.emit(e)
+			return err
+		}
+		e.Type = EventStarted
+		j.emit(e)
+		e.Type, e.Size = EventCompleted, 0
+		j.emit(e)
+	}
+	return nil
+}
+
+func (j *job) createSymlink(l symlink) error {
+	dir := filepath.Dir(l.name)
+	if err := j.checkInsideRoot(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if !j.options.AllowSymlinkEscape {
+		if filepath.IsAbs(l.target) || filepath.VolumeName(l.target) != "" {
+			return errSymlinkEscape
+		}
+		// the cleaned target has ".." only at the beginning,
+		// so they go up from the real directory of the link.
+		realDir, err := resolvePath(dir)
+		if err != nil {
+			return err
+		}
+		if err := j.checkInsideRoot(filepath.Join(realDir, l.target)); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(l.name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(l.target, l.name)
+}
+
+// errSymlinkEscape means that the path goes outside of the destination through the symbolic links.
+var errSymlinkEscape = errors.New("transfer: the symbolic link points outside of the destination")
+
+// checkInsideRoot checks that the path is resolved inside the destination root,
+// following the existing symbolic links.
+// It returns errSymlinkEscape if it isn't, unless AllowSymlinkEscape is set.
+func (j *job) checkInsideRoot(name string) error {
+	if j.options.AllowSymlinkEscape {
+		return nil
+	}
+	root, err := resolvePath(j.root)
+	if err != nil {
+		return err
+	}
+	p, err := resolvePath(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// a broken link. it might be created to point anywhere.
+		return errSymlinkEscape
+	}
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errSymlinkEscape
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path of name with the symbolic links resolved.
+// The missing part of name is kept as it is, because it is created as directories.
+func resolvePath(name string) (string, error) {
+	p, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for {
+		_, err := os.Lstat(p)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			break
+		}
+		rest = append(rest, filepath.Base(p))
+		p = parent
+	}
+	p, err = filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	for _, elem := range slices.Backward(rest) {
+		p = filepath.Join(p, elem)
+	}
+	return p, nil
+}
@@ -0,0 +1,320 @@
+package transfer
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/shogo82148/s3cli-mini/internal/testtree"
+)
+
+// writeTree writes the files to a temporary directory, and returns its path.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	switch runtime.GOOS {
+	case "windows", "plan9":
+		t.Skipf("skipping on %s", runtime.GOOS)
+	}
+	dir := t.TempDir()
+	testtree.Write(t, dir, files)
+	return dir
+}
+
+// symlinkFixture returns the fixture of fastwalk with a link to an ancestor and a link to a file.
+func symlinkFixture() map[string]string {
+	files := testtree.Symlinks()
+	files["foo/loop"] = "LINK:.."
+	files["baz/link.go"] = "LINK:../foo/foo.go"
+	return files
+}
+
+func uploadSymlinkFixture(t *testing.T, mode SymlinkMode) (*fakeUploaderAPI, *eventRecorder) {
+	t.Helper()
+	dir := writeTree(t, symlinkFixture())
+	svc := newFakeUploaderAPI()
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.Symlinks = mode
+		o.EventHandler = events
+	})
+	if err := c.UploadDir(t.Context(), dir, "bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	return svc, events
+}
+
+func skippedReasons(events *eventRecorder) map[string]string {
+	reasons := map[string]string{}
+	for _, e := range events.events {
+		if e.Type == EventSkipped {
+			reasons[e.Destination] = e.Reason
+		}
+	}
+	return reasons
+}
+
+func TestUploadDir_SymlinkFollow(t *testing.T) {
+	svc, events := uploadSymlinkFixture(t, SymlinkFollow)
+
+	keys := slices.Sorted(maps.Keys(svc.objects))
+	want := []string{"baz/link.go", "foo/foo.go", "symdir/foo.go"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("want %v, got %v", want, keys)
+	}
+	if got := string(svc.objects["baz/link.go"]); got != "one" {
+		t.Errorf("want the content of the target, got %q", got)
+	}
+
+	wantSkipped := map[string]string{
+		"s3://bucket/bar/bar.go":  SkipReasonBrokenSymlink,
+		"s3://bucket/foo/loop":    SkipReasonSymlinkLoop,
+		"s3://bucket/symdir/loop": SkipReasonSymlinkLoop,
+	}
+	if got := skippedReasons(events); !maps.Equal(got, wantSkipped) {
+		t.Errorf("want %v, got %v", wantSkipped, got)
+	}
+}
+
+func TestUploadDir_SymlinkSkip(t *testing.T) {
+	svc, events := uploadSymlinkFixture(t, SymlinkSkip)
+
+	keys := slices.Sorted(maps.Keys(svc.objects))
+	if want := []string{"foo/foo.go"}; !slices.Equal(keys, want) {
+		t.Errorf("want %v, got %v", want, keys)
+	}
+	if n := events.count(EventSkipped); n != 0 {
+		t.Errorf("want no skipped events, got %d", n)
+	}
+}
+
+func TestUploadDir_SymlinkPreserve(t *testing.T) {
+	svc, _ := uploadSymlinkFixture(t, SymlinkPreserve)
+
+	keys := slices.Sorted(maps.Keys(svc.objects))
+	want := []string{"bar/bar.go", "baz/link.go", "foo/foo.go", "foo/loop", "symdir"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("want %v, got %v", want, keys)
+	}
+	targets := map[string]string{
+		"bar/bar.go":  "../foo.go",
+		"baz/link.go": "../foo/foo.go",
+		"foo/loop":    "..",
+		"symdir":      "foo",
+	}
+	for key, target := range targets {
+		if got := svc.metadata[key][metadataSymlink]; got != target {
+			t.Errorf("%s: want %q, got %q", key, target, got)
+		}
+		if len(svc.objects[key]) != 0 {
+			t.Errorf("%s: want an empty object, got %q", key, svc.objects[key])
+		}
+	}
+	if _, ok := svc.metadata["foo/foo.go"][metadataSymlink]; ok {
+		t.Error("foo/foo.go: want no link target")
+	}
+}
+
+func TestCreateSymlinks(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"foo/foo.go": "one",
+		"symdir":     "",
+		"bar/bar.go": "",
+	})
+
+	j := New(newFakeUploaderAPI()).newJob(t.Context())
+	j.root = dir
+	j.addSymlink(Event{}, filepath.Join(dir, "symdir"), "foo")
+	j.addSymlink(Event{}, filepath.Join(dir, "bar", "bar.go"), "../foo/foo.go")
+	if err := j.createSymlinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := os.Readlink(filepath.Join(dir, "symdir")); err != nil || got != "foo" {
+		t.Errorf("want foo, got %q, %v", got, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "bar", "bar.go"))
+	if err != nil || string(data) != "one" {
+		t.Errorf("want one, got %q, %v", data, err)
+	}
+}
+
+func TestDownloadDir_SymlinkPreserve(t *testing.T) {
+	svc, _ := uploadSymlinkFixture(t, SymlinkPreserve)
+
+	dir := writeTree(t, nil)
+	c := New(svc, func(o *Options) {
+		o.Symlinks = SymlinkPreserve
+	})
+	// the second download must not write through the links of the first one.
+	for i := range 2 {
+		if err := c.DownloadDir(t.Context(), "bucket", "", dir); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+
+	targets := map[string]string{
+		"bar/bar.go":  "../foo.go",
+		"baz/link.go": "../foo/foo.go",
+		"foo/loop":    "..",
+		"symdir":      "foo",
+	}
+	for name, target := range targets {
+		got, err := os.Readlink(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != filepath.FromSlash(target) {
+			t.Errorf("%s: want %q, got %q", name, target, got)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "foo", "foo.go")); err != nil || string(data) != "one" {
+		t.Errorf("want one, got %q, %v", data, err)
+	}
+}
+
+// downloadEscapeFixture downloads the links to outside of the destination.
+func downloadEscapeFixture(t *testing.T, allow bool) (dir, outside string, events *eventRecorder) {
+	t.Helper()
+	outside = filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := newFakeUploaderAPI()
+	svc.objects["absolute.txt"] = []byte{}
+	svc.metadata["absolute.txt"] = map[string]string{metadataSymlink: outside}
+	svc.objects["dst/parent.txt"] = []byte{}
+	svc.metadata["dst/parent.txt"] = map[string]string{metadataSymlink: "../../outside.txt"}
+	svc.objects["dst/inside.txt"] = []byte{}
+	svc.metadata["dst/inside.txt"] = map[string]string{metadataSymlink: "sub/../../dst/foo.txt"}
+
+	parent := writeTree(t, nil)
+	dir = filepath.Join(parent, "dst")
+	events = &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.Symlinks = SymlinkPreserve
+		o.AllowSymlinkEscape = allow
+		o.EventHandler = events
+	})
+	if err := c.DownloadDir(t.Context(), "bucket", "", dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir, outside, events
+}
+
+func TestDownloadDir_SymlinkPreserve_Escape(t *testing.T) {
+	dir, _, events := downloadEscapeFixture(t, false)
+
+	skipped := map[string]string{}
+	for _, e := range events.events {
+		if e.Type == EventSkipped {
+			skipped[e.Source] = e.Reason
+		}
+	}
+	want := map[string]string{
+		"s3://bucket/absolute.txt":   SkipReasonSymlinkEscape,
+		"s3://bucket/dst/parent.txt": SkipReasonSymlinkEscape,
+	}
+	if !maps.Equal(skipped, want) {
+		t.Errorf("want %v, got %v", want, skipped)
+	}
+	for _, name := range []string{"absolute.txt", "dst/parent.txt"} {
+		if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("%s: want no link, got %v", name, err)
+		}
+	}
+
+	// the target is cleaned, and it stays inside of the destination.
+	got, err := os.Readlink(filepath.Join(dir, "dst", "inside.txt"))
+	if want := filepath.FromSlash("../dst/foo.txt"); err != nil || got != want {
+		t.Errorf("want %q, got %q, %v", want, got, err)
+	}
+}
+
+func TestDownloadDir_SymlinkPreserve_AllowEscape(t *testing.T) {
+	dir, outside, events := downloadEscapeFixture(t, true)
+
+	if n := events.count(EventSkipped); n != 0 {
+		t.Errorf("want no skipped, got %d", n)
+	}
+	targets := map[string]string{
+		"absolute.txt":   outside,
+		"dst/parent.txt": "../../outside.txt",
+	}
+	for name, target := range targets {
+		got, err := os.Readlink(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || got != filepath.FromSlash(target) {
+			t.Errorf("%s: want %q, got %q, %v", name, target, got, err)
+		}
+	}
+}
+
+func TestDownloadDir_SymlinkPreserve_ParentLink(t *testing.T) {
+	outside := t.TempDir()
+	dir := writeTree(t, map[string]string{
+		// the links to directories that a previous download created.
+		"escape":    "LINK:" + outside,
+		"inside":    "LINK:foo",
+		"foo/.keep": "",
+	})
+	svc := newFakeUploaderAPI()
+	svc.objects["escape/file.txt"] = []byte("two")
+	svc.objects["escape/link.txt"] = []byte{}
+	svc.metadata["escape/link.txt"] = map[string]string{metadataSymlink: "file.txt"}
+	svc.objects["inside/file.txt"] = []byte("three")
+
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.Symlinks = SymlinkPreserve
+		o.EventHandler = events
+	})
+	if err := c.DownloadDir(t.Context(), "bucket", "", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := events.count(EventSkipped); n != 2 {
+		t.Errorf("want 2 skipped, got %d", n)
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("want nothing written outside, got %v", entries)
+	}
+	// the links inside of the destination are followed.
+	if data, err := os.ReadFile(filepath.Join(dir, "foo", "file.txt")); err != nil || string(data) != "three" {
+		t.Errorf("want three, got %q, %v", data, err)
+	}
+}
+
+func TestDownloadDir_SymlinkPreserve_ReplaceLink(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"foo/foo.go": "one",
+		"link.go":    "LINK:foo/foo.go",
+	})
+	svc := newFakeUploaderAPI()
+	svc.objects["link.go"] = []byte("two")
+
+	c := New(svc, func(o *Options) {
+		o.Symlinks = SymlinkPreserve
+	})
+	if err := c.DownloadDir(t.Context(), "bucket", "", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// the regular object replaces the link, instead of writing through it.
+	info, err := os.Lstat(filepath.Join(dir, "link.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Errorf("want a regular file, got %v", info.Mode())
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "foo", "foo.go")); err != nil || string(data) != "one" {
+		t.Errorf("want one, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,143 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// throttler pauses starting new requests while S3 is throttling the requests, e.g. 503 SlowDown.
+// The workers share it, so they back off together instead of retrying independently.
+type throttler struct {
+	mu    sync.Mutex
+	until time.Time
+	now   func() time.Time
+}
+
+func newThrottler() *throttler {
+	return &throttler{
+		now: time.Now,
+	}
+}
+
+// observe records that a request is throttled and will be retried after the delay.
+func (t *throttler) observe(delay time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := t.now().Add(delay); until.After(t.until) {
+		t.until = until
+	}
+}
+
+// wait waits for the end of the back off.
+func (t *throttler) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		d := t.until.Sub(t.now())
+		t.mu.Unlock()
+		if d <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// throttledAPI reports the throttling errors of the requests to the throttler.
+type throttledAPI struct {
+	S3API
+	optFn func(*s3.Options)
+}
+
+func (a *throttledAPI) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return a.S3API.AbortMultipartUpload(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return a.S3API.CompleteMultipartUpload(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return a.S3API.CopyObject(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return a.S3API.CreateMultipartUpload(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return a.S3API.GetObject(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return a.S3API.HeadObject(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return a.S3API.ListObjectsV2(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return a.S3API.PutObject(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return a.S3API.UploadPart(ctx, params, append(optFns, a.optFn)...)
+}
+
+func (a *throttledAPI) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return a.S3API.UploadPartCopy(ctx, params, append(optFns, a.optFn)...)
+}
+
+// withRetryer returns an option of S3 client that reports the throttling errors to the throttler.
+func (t *throttler) withRetryer() func(*s3.Options) {
+	return func(o *s3.Options) {
+		if o.Retryer == nil {
+			return
+		}
+		o.Retryer = &throttleRetryer{
+			RetryerV2: asRetryerV2(o.Retryer),
+			throttler: t,
+		}
+	}
+}
+
+var throttleErrors = retry.IsErrorThrottles(retry.DefaultThrottles)
+
+type throttleRetryer struct {
+	aws.RetryerV2
+	throttler *throttler
+}
+
+func (r *throttleRetryer) RetryDelay(attempt int, err error) (time.Duration, error) {
+	delay, retryErr := r.RetryerV2.RetryDelay(attempt, err)
+	if retryErr == nil && throttleErrors.IsErrorThrottle(err) == aws.TrueTernary {
+		r.throttler.observe(delay)
+	}
+	return delay, retryErr
+}
+
+func asRetryerV2(r aws.Retryer) aws.RetryerV2 {
+	if v, ok := r.(aws.RetryerV2); ok {
+		return v
+	}
+	return retryerV2{Retryer: r}
+}
+
+type retryerV2 struct {
+	aws.Retryer
+}
+
+func (r retryerV2) GetAttemptToken(context.Context) (func(error) error, error) {
+	return r.Retryer.GetInitialToken(), nil
+}
@@ -0,0 +1,47 @@
+package transfer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+func TestThrottler(t *testing.T) {
+	throttle := newThrottler()
+	o := &s3.Options{
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(attempt int, err error) (time.Duration, error) {
+				return 100 * time.Millisecond, nil
+			})
+		}),
+	}
+	throttle.withRetryer()(o)
+
+	// non-throttling errors don't pause the workers.
+	if _, err := o.Retryer.RetryDelay(1, errors.New("some error")); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := throttle.wait(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 100*time.Millisecond {
+		t.Errorf("want no wait, but waited %s", d)
+	}
+
+	// throttling errors pause the workers.
+	if _, err := o.Retryer.RetryDelay(1, &smithy.GenericAPIError{Code: "SlowDown"}); err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if err := throttle.wait(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("want wait, but waited only %s", d)
+	}
+}
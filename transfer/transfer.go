@@ -0,0 +1,342 @@
+// Package transfer transfers files and objects between the local file system and Amazon S3.
+// It is the engine of the cp command of s3cli-mini, and has the same behavior as the command.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// DefaultConcurrency is the default number of concurrent requests.
+	DefaultConcurrency = 4
+
+	// DefaultMultipartThreshold is the default size threshold for multipart transfers.
+	DefaultMultipartThreshold = 5 * 1024 * 1024
+
+	// DefaultMultipartChunkSize is the default chunk size for multipart transfers.
+	DefaultMultipartChunkSize = 5 * 1024 * 1024
+
+	// DefaultDownloadPartSize is the default part size for downloading.
+	DefaultDownloadPartSize = 8 * 1024 * 1024
+
+	// DefaultDownloadConcurrency is the default number of concurrent part downloads per object.
+	DefaultDownloadConcurrency = 5
+
+	// DefaultMaxMemory is the default memory budget for downloading to a stream.
+	DefaultMaxMemory = 64 * 1024 * 1024
+
+	// DefaultMaxOpenFiles is the default maximum number of files in flight.
+	DefaultMaxOpenFiles = 64
+
+	// MaxCopyObjectSize is the maximum object size in a single atomic copy operation. (5GiB)
+	// https://docs.aws.amazon.com/AmazonS3/latest/dev/CopyingObjectsExamples.html
+	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+)
+
+// S3API is the S3 API used by Client.
+// *s3.Client implements it.
+type S3API interface {
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+}
+
+// Options is the options of Client.
+// The zero values mean the defaults.
+type Options struct {
+	// Concurrency is the number of concurrent requests.
+	Concurrency int
+
+	// MultipartThreshold is the size threshold for multipart uploads.
+	MultipartThreshold int64
+
+	// MultipartChunkSize is the chunk size for multipart uploads and copies.
+	MultipartChunkSize int64
+
+	// CopyThreshold is the size threshold for multipart copies.
+	// It can't be larger than MaxCopyObjectSize.
+	CopyThreshold int64
+
+	// DownloadPartSize is the part size for downloading.
+	DownloadPartSize int64
+
+	// DownloadConcurrency is the number of concurrent part downloads per object.
+	DownloadConcurrency int
+
+	// MaxMemory is the memory budget for reassembling the parts when downloading to a stream.
+	// If DownloadPartSize is larger than MaxMemory, the parts are downloaded in the size of MaxMemory.
+	MaxMemory int64
+
+	// MaxOpenFiles is the maximum number of files in flight, including the local files opened at the same time.
+	MaxOpenFiles int
+
+	// Symlinks is the handling of the symbolic links when uploading a directory.
+	// With SymlinkPreserve, the symbolic links are also recreated on download.
+	Symlinks SymlinkMode
+
+	// AllowSymlinkEscape allows SymlinkPreserve to create the symbolic links that point outside of the destination,
+	// and to download the files through the existing links to outside of it.
+	// By default, they are reported by EventSkipped with SkipReasonSymlinkEscape.
+	AllowSymlinkEscape bool
+
+	// Preserve stores the modification times, the modes, the owners and the groups of the uploaded files
+	// as the user metadata of the objects, and restores them on download.
+	// The modification times of the objects without the metadata are restored from their LastModified.
+	Preserve bool
+
+	// DryRun reports the transfers by EventSkipped without actually running them.
+	DryRun bool
+
+	// ACL is the canned ACL for the uploaded objects.
+	ACL types.ObjectCannedACL
+
+	// ContentType is the content type for the uploaded objects.
+	// If it is empty, the content type is guessed from the file extension.
+	ContentType string
+
+	// NoGuessMimeType disables guessing the content type. application/octet-stream is used instead.
+	NoGuessMimeType bool
+
+	// CacheControl, ContentDisposition, ContentEncoding, ContentLanguage and Expires
+	// are the headers for the uploaded objects.
+	CacheControl       string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	Expires            *time.Time
+
+	// WebsiteRedirects maps the keys of the uploaded objects to their website redirect locations.
+	// The locations are set as x-amz-website-redirect-location for static website hosting.
+	WebsiteRedirects map[string]string
+
+	// ObjectLockMode and ObjectLockRetainUntilDate are the Object Lock retention for the uploaded and copied objects.
+	// They must be set together.
+	ObjectLockMode            types.ObjectLockMode
+	ObjectLockRetainUntilDate *time.Time
+
+	// ObjectLockLegalHoldStatus is the Object Lock legal hold status for the uploaded and copied objects.
+	ObjectLockLegalHoldStatus types.ObjectLockLegalHoldStatus
+
+	// NoOverwrite uploads and copies the objects only if they don't exist, by If-None-Match: *.
+	// The existing objects are reported by EventSkipped with SkipReasonPreconditionFailed.
+	NoOverwrite bool
+
+	// IfMatch uploads and copies the objects only if their ETags match it, by If-Match.
+	// The objects with other ETags are reported by EventSkipped with SkipReasonPreconditionFailed.
+	IfMatch string
+
+	// EventHandler receives the events of the transfers.
+	EventHandler EventHandler
+}
+
+func (o *Options) setDefaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.MultipartThreshold <= 0 {
+		o.MultipartThreshold = DefaultMultipartThreshold
+	}
+	if o.MultipartChunkSize <= 0 {
+		o.MultipartChunkSize = DefaultMultipartChunkSize
+	}
+	if o.CopyThreshold <= 0 || o.CopyThreshold > MaxCopyObjectSize {
+		o.CopyThreshold = MaxCopyObjectSize
+	}
+	if o.DownloadPartSize <= 0 {
+		o.DownloadPartSize = DefaultDownloadPartSize
+	}
+	if o.DownloadConcurrency <= 0 {
+		o.DownloadConcurrency = DefaultDownloadConcurrency
+	}
+	if o.MaxMemory <= 0 {
+		o.MaxMemory = DefaultMaxMemory
+	}
+	if o.MaxOpenFiles <= 0 {
+		o.MaxOpenFiles = DefaultMaxOpenFiles
+	}
+}
+
+// checksumAlgorithm returns the checksum algorithm for the uploaded objects.
+// Object Lock requires Content-MD5 or a checksum on the uploads, so CRC32 is sent explicitly
+// even if the SDK is configured to calculate the checksums only when required.
+func (o *Options) checksumAlgorithm() types.ChecksumAlgorithm {
+	if o.ObjectLockMode != "" || o.ObjectLockLegalHoldStatus != "" {
+		return types.ChecksumAlgorithmCrc32
+	}
+	return ""
+}
+
+// ifMatch returns the If-Match header of the writes.
+func (o *Options) ifMatch() *string {
+	return nullableString(o.IfMatch)
+}
+
+// ifNoneMatch returns the If-None-Match header of the writes.
+func (o *Options) ifNoneMatch() *string {
+	if o.NoOverwrite {
+		return aws.String("*")
+	}
+	return nil
+}
+
+// Client transfers files and objects.
+// It is safe for concurrent use.
+type Client struct {
+	s3         S3API
+	downloader *transfermanager.Client
+	throttle   *throttler
+	options    Options
+}
+
+// New returns a new Client.
+func New(svc S3API, optFns ...func(*Options)) *Client {
+	var options Options
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	options.setDefaults()
+
+	throttle := newThrottler()
+	svc = &throttledAPI{S3API: svc, optFn: throttle.withRetryer()}
+	downloader := transfermanager.New(svc, func(o *transfermanager.Options) {
+		o.PartSizeBytes = options.DownloadPartSize
+		o.Concurrency = options.DownloadConcurrency
+	})
+	return &Client{
+		s3:         svc,
+		downloader: downloader,
+		throttle:   throttle,
+		options:    options,
+	}
+}
+
+// job is the state of a call of Client's methods.
+type job struct {
+	*Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ctxAbort context.Context
+	sched    *scheduler
+
+	// the buffers for the running parts and the next part to be read.
+	buffers *bufferPool
+
+	mu        sync.Mutex
+	err       error     // the first error of the transfers
+	abortErrs []error   // the errors of cleaning up the multipart uploads
+	symlinks  []symlink // the symbolic links to create after the downloads
+	root      string    // the destination directory of the download, that SymlinkPreserve confines the files to
+}
+
+func (c *Client) newJob(ctx context.Context) *job {
+	ctxAbort := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	o := &c.options
+	return &job{
+		Client:   c,
+		ctx:      ctx,
+		cancel:   cancel,
+		ctxAbort: ctxAbort,
+		buffers:  newBufferPool(int64(o.Concurrency+1) * max(o.MultipartChunkSize, o.MultipartThreshold)),
+	}
+}
+
+// schedule starts the scheduler, and runs walk that submits the files to it.
+// It waits for all the transfers, and returns the first error.
+func (j *job) schedule(walk func(s *scheduler) error) error {
+	defer j.cancel()
+	j.sched = newScheduler(j.ctx, j.options.Concurrency, j.options.MaxOpenFiles, j.throttle)
+	if err := walk(j.sched); err != nil {
+		j.setError(err)
+	}
+	j.sched.wait()
+	return j.result()
+}
+
+// setError records the first error, and cancels the other transfers.
+// It returns false if the transfers are already canceled.
+func (j *job) setError(err error) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil || j.ctx.Err() != nil {
+		// the transfers are already canceled. err may be caused by the cancellation.
+		return false
+	}
+	j.err = err
+	j.cancel()
+	return true
+}
+
+// fail reports the failure of the transfer, and cancels the other transfers.
+func (j *job) fail(e Event, err error) {
+	if j.setError(err) {
+		e.Type = EventFailed
+		e.Err = err
+		j.emit(e)
+	}
+}
+
+// failOrSkip reports the failure of the transfer, and cancels the other transfers.
+// If err is the failure of the conditional write, the transfer is skipped instead, and the others go on.
+func (j *job) failOrSkip(e Event, err error) {
+	if isPreconditionFailed(err) {
+		j.skip(e, SkipReasonPreconditionFailed)
+		return
+	}
+	j.fail(e, err)
+}
+
+// isPreconditionFailed reports whether err is 412 Precondition Failed.
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed"
+}
+
+// skip reports that the transfer is skipped.
+func (j *job) skip(e Event, reason string) {
+	e.Type = EventSkipped
+	e.Reason = reason
+	j.emit(e)
+}
+
+func (j *job) abortError(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.abortErrs = append(j.abortErrs, err)
+}
+
+// result returns the first error of the transfers.
+func (j *job) result() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.err
+	if err == nil {
+		err = j.ctx.Err()
+	}
+	if err == nil || len(j.abortErrs) == 0 {
+		return err
+	}
+	return errors.Join(append([]error{err}, j.abortErrs...)...)
+}
+
+func (j *job) emit(e Event) {
+	if h := j.options.EventHandler; h != nil {
+		h.HandleEvent(e)
+	}
+}
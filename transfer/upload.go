@@ -0,0 +1,546 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/shogo82148/s3cli-mini/internal/fastwalk"
+)
+
+// Upload uploads the stream r to s3://bucket/key.
+// r is not closed.
+func (c *Client) Upload(ctx context.Context, r io.Reader, bucket, key string) error {
+	j := c.newJob(ctx)
+	e := Event{
+		Operation:   OperationUpload,
+		Source:      StreamLocation,
+		Destination: s3URI(bucket, key),
+		Size:        -1,
+	}
+	if c.options.DryRun {
+		j.skip(e, SkipReasonDryRun)
+		return nil
+	}
+	return j.schedule(func(s *scheduler) error {
+		s.addFile(func() {
+			if !s.acquire() {
+				return
+			}
+			u := &uploader{
+				job:      j,
+				event:    e,
+				body:     r,
+				bucket:   bucket,
+				key:      key,
+				redirect: j.options.WebsiteRedirects[key],
+				done:     s.release,
+			}
+			u.upload()
+		})
+		return nil
+	})
+}
+
+// UploadFile uploads the local file to s3://bucket/key.
+func (c *Client) UploadFile(ctx context.Context, name, bucket, key string) error {
+	j := c.newJob(ctx)
+	if c.options.DryRun {
+		j.skip(Event{
+			Operation:   OperationUpload,
+			Source:      name,
+			Destination: s3URI(bucket, key),
+			Size:        -1,
+		}, SkipReasonDryRun)
+		return nil
+	}
+	return j.schedule(func(s *scheduler) error {
+		s.addFile(func() {
+			j.uploadFile(name, bucket, key)
+		})
+		return nil
+	})
+}
+
+// UploadDir uploads the files in the local directory to s3://bucket/prefix.
+// The symbolic links are handled by Options.Symlinks.
+func (c *Client) UploadDir(ctx context.Context, dir, bucket, prefix string) error {
+	j := c.newJob(ctx)
+	return j.schedule(func(s *scheduler) error {
+		return fastwalk.Walk(dir, func(p string, typ os.FileMode) error {
+			if err := j.ctx.Err(); err != nil {
+				return err
+			}
+			if typ.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			key := path.Join(prefix, filepath.ToSlash(rel))
+			e := Event{
+				Operation:   OperationUpload,
+				Source:      p,
+				Destination: s3URI(bucket, key),
+				Size:        -1,
+			}
+
+			var target string
+			if typ == os.ModeSymlink {
+				switch c.options.Symlinks {
+				case SymlinkSkip:
+					return nil
+				case SymlinkPreserve:
+					target, err = os.Readlink(p)
+					if err != nil {
+						return err
+					}
+				default:
+					info, err := os.Stat(p)
+					if err != nil {
+						j.skip(e, SkipReasonBrokenSymlink)
+						return nil
+					}
+					if info.IsDir() {
+						loop, err := isSymlinkLoop(p, info)
+						if err != nil {
+							return err
+						}
+						if loop {
+							j.skip(e, SkipReasonSymlinkLoop)
+							return nil
+						}
+						return fastwalk.TraverseLink
+					}
+				}
+			}
+			if c.options.DryRun {
+				j.skip(e, SkipReasonDryRun)
+				return nil
+			}
+
+			// the file is opened by the file workers, not by the walker,
+			// so the number of open files is bounded by the scheduler.
+			if target != "" {
+				s.addFile(func() {
+					j.uploadSymlink(e, bucket, key, target)
+				})
+				return nil
+			}
+			s.addFile(func() {
+				j.uploadFile(p, bucket, key)
+			})
+			return nil
+		})
+	})
+}
+
+// uploadFile uploads the local file in the scheduler.
+func (j *job) uploadFile(name, bucket, key string) {
+	e := Event{
+		Operation:   OperationUpload,
+		Source:      name,
+		Destination: s3URI(bucket, key),
+		Size:        -1,
+	}
+	if j.ctx.Err() != nil {
+		return
+	}
+	f, err := j.sched.openFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		j.fail(e, err)
+		return
+	}
+	var metadata map[string]string
+	if j.options.Preserve {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			j.fail(e, err)
+			return
+		}
+		metadata = fileMetadata(info)
+	}
+	u := &uploader{
+		job:      j,
+		event:    e,
+		body:     f,
+		bucket:   bucket,
+		key:      key,
+		redirect: j.options.WebsiteRedirects[key],
+		metadata: metadata,
+		done:     func() { f.Close() },
+	}
+	u.upload()
+}
+
+// UploadRedirects creates the empty objects that redirect to other locations, for static website hosting.
+// redirects maps the keys of the objects to the redirect locations.
+func (c *Client) UploadRedirects(ctx context.Context, bucket string, redirects map[string]string) error {
+	j := c.newJob(ctx)
+	keys := make([]string, 0, len(redirects))
+	for key := range redirects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return j.schedule(func(s *scheduler) error {
+		for _, key := range keys {
+			e := Event{
+				Operation:   OperationRedirect,
+				Source:      s3URI(bucket, key),
+				Destination: redirects[key],
+				Size:        0,
+			}
+			if c.options.DryRun {
+				j.skip(e, SkipReasonDryRun)
+				continue
+			}
+			s.addFile(func() {
+				if !s.acquire() {
+					return
+				}
+				u := &uploader{
+					job:      j,
+					event:    e,
+					body:     bytes.NewReader(nil),
+					bucket:   bucket,
+					key:      key,
+					redirect: redirects[key],
+					done:     s.release,
+				}
+				u.upload()
+			})
+		}
+		return nil
+	})
+}
+
+type uploader struct {
+	*job
+	event     Event
+	body      io.Reader
+	bucket    string
+	key       string
+	readerPos int64
+	totalSize int64
+
+	// redirect is the website redirect location of the object.
+	redirect string
+
+	// metadata is the user metadata of the object.
+	metadata map[string]string
+
+	// done is called after the upload finishes, to close the body and release its slot.
+	done func()
+
+	mu    sync.Mutex
+	parts completedParts
+}
+
+type completedParts []types.CompletedPart
+
+func (a completedParts) Len() int      { return len(a) }
+func (a completedParts) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a completedParts) Less(i, j int) bool {
+	return aws.ToInt32(a[i].PartNumber) < aws.ToInt32(a[j].PartNumber)
+}
+
+// upload runs in a file worker of the scheduler.
+// It reads the first chunk, and submits the parts to the scheduler.
+func (u *uploader) upload() {
+	u.initSize()
+	u.event.Size = u.totalSize
+	u.emitEvent(EventStarted, 0)
+
+	// read the first chunk. if it reaches EOF within the threshold, the body is small enough to upload in a single part.
+	// the body larger than the threshold is uploaded in multipart, even if it fits in a chunk.
+	size := u.options.MultipartChunkSize
+	if u.totalSize < 0 || u.totalSize <= u.options.MultipartThreshold {
+		size = max(size, u.options.MultipartThreshold)
+	}
+	r, buf, n, err := u.nextReader(size)
+	if err == io.EOF && n <= u.options.MultipartThreshold {
+		u.sched.transfer(false, func() {
+			u.singlePartUpload(r, buf)
+		})
+		return
+	}
+	if err != nil && err != io.EOF {
+		u.buffers.put(buf)
+		u.done()
+		u.fail(u.event, err)
+		return
+	}
+
+	// start multipart upload
+	resp, err := u.s3.CreateMultipartUpload(u.ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    aws.String(u.bucket),
+		Key:                       aws.String(u.key),
+		ACL:                       u.options.ACL,
+		ContentType:               u.contentType(u.key),
+		CacheControl:              nullableString(u.options.CacheControl),
+		ContentDisposition:        nullableString(u.options.ContentDisposition),
+		ContentEncoding:           nullableString(u.options.ContentEncoding),
+		ContentLanguage:           nullableString(u.options.ContentLanguage),
+		Expires:                   u.options.Expires,
+		WebsiteRedirectLocation:   nullableString(u.redirect),
+		Metadata:                  u.metadata,
+		ObjectLockMode:            u.options.ObjectLockMode,
+		ObjectLockRetainUntilDate: u.options.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: u.options.ObjectLockLegalHoldStatus,
+		ChecksumAlgorithm:         u.options.checksumAlgorithm(),
+	})
+	if err != nil {
+		u.buffers.put(buf)
+		u.done()
+		u.fail(u.event, err)
+		return
+	}
+	uploadID := aws.ToString(resp.UploadId)
+
+	// the rest of the body is read by a feeder, so the file worker can go on to the next file.
+	u.sched.feed(func() {
+		var wg sync.WaitGroup
+		num := int32(1)
+		for {
+			u.submitChunk(&wg, uploadID, num, r, buf, n)
+			if err == io.EOF || u.ctx.Err() != nil {
+				break
+			}
+			num++
+
+			r, buf, n, err = u.nextReader(u.options.MultipartChunkSize)
+			if err != nil && err != io.EOF {
+				u.buffers.put(buf)
+				u.fail(u.event, err)
+				break
+			}
+			if n == 0 {
+				u.buffers.put(buf)
+				break
+			}
+		}
+
+		u.sched.complete(func() {
+			wg.Wait()
+			u.done()
+			u.completeUpload(uploadID)
+		})
+	})
+}
+
+func (u *uploader) submitChunk(wg *sync.WaitGroup, uploadID string, num int32, r io.ReadSeeker, buf []byte, size int64) {
+	wg.Add(1)
+	u.sched.transfer(true, func() {
+		defer wg.Done()
+		defer u.buffers.put(buf)
+		if u.ctx.Err() != nil {
+			return
+		}
+		u.uploadChunk(uploadID, num, r, size)
+	})
+}
+
+func (u *uploader) completeUpload(uploadID string) {
+	if u.ctx.Err() != nil {
+		// the request is aborted
+		u.abortUpload(uploadID)
+		return
+	}
+	sort.Sort(u.parts)
+	_, err := u.s3.CompleteMultipartUpload(u.ctxAbort, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+		IfMatch:         u.options.ifMatch(),
+		IfNoneMatch:     u.options.ifNoneMatch(),
+	})
+	if isPreconditionFailed(err) {
+		// the upload is skipped, and the others go on. clean up the parts.
+		u.abortUpload(uploadID)
+		u.skip(u.event, SkipReasonPreconditionFailed)
+		return
+	}
+	if err != nil {
+		u.fail(u.event, err)
+		return
+	}
+	u.emitEvent(EventCompleted, 0)
+}
+
+func (u *uploader) abortUpload(uploadID string) {
+	_, err := u.s3.AbortMultipartUpload(u.ctxAbort, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		u.abortError(fmt.Errorf("failed to abort multipart upload of %s: %w", u.event.Destination, err))
+	}
+}
+
+func (u *uploader) initSize() {
+	u.totalSize = -1
+	switch body := u.body.(type) {
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := body.Stat()
+		if err != nil {
+			return
+		}
+		if !info.Mode().IsRegular() {
+			// non-regular file, Size is system-dependent.
+			return
+		}
+		u.totalSize = info.Size()
+	case interface{ Len() int }:
+		u.totalSize = int64(body.Len())
+	case io.Seeker:
+		current, err := body.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return
+		}
+		end, err := body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return
+		}
+		_, err = body.Seek(current, io.SeekStart)
+		if err != nil {
+			return
+		}
+		u.totalSize = end - current
+	}
+}
+
+// nextReader returns the reader of the next chunk.
+// If the body is read into a buffer from the pool, the buffer is also returned.
+// The caller must return it to the pool after the chunk is uploaded.
+func (u *uploader) nextReader(size int64) (io.ReadSeeker, []byte, int64, error) {
+	if u.totalSize >= 0 {
+		switch r := u.body.(type) {
+		case io.ReaderAt:
+			var err error
+			n := size
+			if remain := u.totalSize - u.readerPos; remain <= n {
+				n = remain
+				err = io.EOF
+			}
+			reader := io.NewSectionReader(r, u.readerPos, n)
+			u.readerPos += n
+			return reader, nil, n, err
+		}
+	}
+
+	buf, err := u.buffers.get(u.ctx, int(size))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	n, err := io.ReadFull(u.body, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	u.readerPos += int64(n)
+	return bytes.NewReader(buf[:n]), buf, int64(n), err
+}
+
+func (u *uploader) singlePartUpload(r io.ReadSeeker, buf []byte) {
+	defer u.done()
+	defer u.buffers.put(buf)
+	if u.ctx.Err() != nil {
+		return
+	}
+	input := &s3.PutObjectInput{
+		Body:                      r,
+		Bucket:                    aws.String(u.bucket),
+		Key:                       aws.String(u.key),
+		ACL:                       u.options.ACL,
+		ContentType:               u.contentType(u.key),
+		CacheControl:              nullableString(u.options.CacheControl),
+		ContentDisposition:        nullableString(u.options.ContentDisposition),
+		ContentEncoding:           nullableString(u.options.ContentEncoding),
+		ContentLanguage:           nullableString(u.options.ContentLanguage),
+		Expires:                   u.options.Expires,
+		WebsiteRedirectLocation:   nullableString(u.redirect),
+		Metadata:                  u.metadata,
+		ObjectLockMode:            u.options.ObjectLockMode,
+		ObjectLockRetainUntilDate: u.options.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: u.options.ObjectLockLegalHoldStatus,
+		ChecksumAlgorithm:         u.options.checksumAlgorithm(),
+		IfMatch:                   u.options.ifMatch(),
+		IfNoneMatch:               u.options.ifNoneMatch(),
+	}
+	_, err := u.s3.PutObject(u.ctx, input)
+	if err != nil {
+		u.failOrSkip(u.event, err)
+		return
+	}
+	u.emitEvent(EventProgress, u.readerPos)
+	u.emitEvent(EventCompleted, 0)
+}
+
+func (u *uploader) uploadChunk(uploadID string, num int32, r io.ReadSeeker, size int64) {
+	resp, err := u.s3.UploadPart(u.ctx, &s3.UploadPartInput{
+		Bucket:            aws.String(u.bucket),
+		Key:               aws.String(u.key),
+		Body:              r,
+		UploadId:          aws.String(uploadID),
+		PartNumber:        aws.Int32(num),
+		ChecksumAlgorithm: u.options.checksumAlgorithm(),
+	})
+	if err != nil {
+		u.fail(u.event, err)
+		return
+	}
+	part := types.CompletedPart{ETag: resp.ETag, PartNumber: aws.Int32(num), ChecksumCRC32: resp.ChecksumCRC32}
+	u.mu.Lock()
+	u.parts = append(u.parts, part)
+	u.mu.Unlock()
+	u.emitEvent(EventProgress, size)
+}
+
+func (u *uploader) emitEvent(typ EventType, bytes int64) {
+	e := u.event
+	e.Type = typ
+	e.Bytes = bytes
+	u.emit(e)
+}
+
+// contentType returns the content type of the uploaded object.
+func (j *job) contentType(path string) *string {
+	if j.options.ContentType != "" {
+		return aws.String(j.options.ContentType)
+	}
+	if j.options.NoGuessMimeType {
+		return aws.String("application/octet-stream")
+	}
+
+	// guess content type
+	var t string
+	if idx := strings.LastIndex(path, "."); idx >= 0 {
+		t = mime.TypeByExtension(path[idx:])
+	}
+	if t == "" {
+		t = "application/octet-stream"
+	}
+	return aws.String(t)
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,577 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeUploaderAPI is a fake S3 that stores the uploaded objects in memory.
+type fakeUploaderAPI struct {
+	S3API
+
+	mu        sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]map[int32][]byte
+	redirects map[string]string
+	locks     map[string]objectLock
+	aborted   []string
+	metadata  map[string]map[string]string
+}
+
+// checkPrecondition checks the conditional write like S3. The ETag of an object is its content.
+// f.mu must be held.
+func (f *fakeUploaderAPI) checkPrecondition(key string, ifMatch, ifNoneMatch *string) error {
+	data, exists := f.objects[key]
+	if aws.ToString(ifNoneMatch) == "*" && exists {
+		return &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	if ifMatch != nil && (!exists || *ifMatch != strconv.Quote(string(data))) {
+		return &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	return nil
+}
+
+// objectLock is the Object Lock settings of an uploaded object.
+type objectLock struct {
+	mode        types.ObjectLockMode
+	retainUntil time.Time
+	legalHold   types.ObjectLockLegalHoldStatus
+	checksum    types.ChecksumAlgorithm
+}
+
+func newFakeUploaderAPI() *fakeUploaderAPI {
+	return &fakeUploaderAPI{
+		objects:   map[string][]byte{},
+		uploads:   map[string]map[int32][]byte{},
+		redirects: map[string]string{},
+		locks:     map[string]objectLock{},
+		metadata:  map[string]map[string]string{},
+	}
+}
+
+func (f *fakeUploaderAPI) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkPrecondition(aws.ToString(params.Key), params.IfMatch, params.IfNoneMatch); err != nil {
+		return nil, err
+	}
+	f.objects[aws.ToString(params.Key)] = data
+	if params.Metadata != nil {
+		f.metadata[aws.ToString(params.Key)] = params.Metadata
+	}
+	if params.WebsiteRedirectLocation != nil {
+		f.redirects[aws.ToString(params.Key)] = aws.ToString(params.WebsiteRedirectLocation)
+	}
+	if params.ObjectLockMode != "" || params.ObjectLockLegalHoldStatus != "" {
+		f.locks[aws.ToString(params.Key)] = objectLock{
+			mode:        params.ObjectLockMode,
+			retainUntil: aws.ToTime(params.ObjectLockRetainUntilDate),
+			legalHold:   params.ObjectLockLegalHoldStatus,
+			checksum:    params.ChecksumAlgorithm,
+		}
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeUploaderAPI) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := strconv.Itoa(len(f.uploads))
+	f.uploads[id] = map[int32][]byte{}
+	if params.Metadata != nil {
+		f.metadata[aws.ToString(params.Key)] = params.Metadata
+	}
+	if params.WebsiteRedirectLocation != nil {
+		f.redirects[aws.ToString(params.Key)] = aws.ToString(params.WebsiteRedirectLocation)
+	}
+	if params.ObjectLockMode != "" || params.ObjectLockLegalHoldStatus != "" {
+		f.locks[aws.ToString(params.Key)] = objectLock{
+			mode:        params.ObjectLockMode,
+			retainUntil: aws.ToTime(params.ObjectLockRetainUntilDate),
+			legalHold:   params.ObjectLockLegalHoldStatus,
+			checksum:    params.ChecksumAlgorithm,
+		}
+	}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (f *fakeUploaderAPI) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	num := aws.ToInt32(params.PartNumber)
+	f.uploads[aws.ToString(params.UploadId)][num] = data
+	out := &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf(`"%d"`, num))}
+	if params.ChecksumAlgorithm == types.ChecksumAlgorithmCrc32 {
+		out.ChecksumCRC32 = aws.String(fmt.Sprintf("crc32-%d", num))
+	}
+	return out, nil
+}
+
+func (f *fakeUploaderAPI) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkPrecondition(aws.ToString(params.Key), params.IfMatch, params.IfNoneMatch); err != nil {
+		return nil, err
+	}
+	parts := f.uploads[aws.ToString(params.UploadId)]
+	var buf bytes.Buffer
+	for i, part := range params.MultipartUpload.Parts {
+		if aws.ToInt32(part.PartNumber) != int32(i+1) {
+			return nil, fmt.Errorf("unexpected part number: %d", aws.ToInt32(part.PartNumber))
+		}
+		if lock, ok := f.locks[aws.ToString(params.Key)]; ok && lock.checksum != "" && part.ChecksumCRC32 == nil {
+			return nil, fmt.Errorf("the checksum of part %d is missing", i+1)
+		}
+		buf.Write(parts[int32(i+1)])
+	}
+	f.objects[aws.ToString(params.Key)] = buf.Bytes()
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeUploaderAPI) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.uploads, aws.ToString(params.UploadId))
+	f.aborted = append(f.aborted, aws.ToString(params.Key))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *fakeUploaderAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(data))),
+		Metadata:      f.metadata[aws.ToString(params.Key)],
+	}, nil
+}
+
+func (f *fakeUploaderAPI) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := &s3.ListObjectsV2Output{}
+	for key, data := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			out.Contents = append(out.Contents, types.Object{
+				Key:  aws.String(key),
+				Size: aws.Int64(int64(len(data))),
+			})
+		}
+	}
+	return out, nil
+}
+
+// GetObject returns the whole object as a single part.
+func (f *fakeUploaderAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: aws.Int64(int64(len(data))),
+		PartsCount:    aws.Int32(1),
+		Metadata:      f.metadata[aws.ToString(params.Key)],
+	}, nil
+}
+
+// eventRecorder records the events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) count(typ EventType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, e := range r.events {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUploadDir(t *testing.T) {
+	dir := t.TempDir()
+	want := map[string][]byte{}
+	var total int64
+	for i := range 20 {
+		// mix the small files and the large files.
+		content := bytes.Repeat([]byte(strconv.Itoa(i)), (i%4)*10+1)
+		name := fmt.Sprintf("file-%02d", i)
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		want["prefix/"+name] = content
+		total += int64(len(content))
+	}
+
+	svc := newFakeUploaderAPI()
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.Concurrency = 1
+		o.MultipartThreshold = 8
+		o.MultipartChunkSize = 8
+		o.EventHandler = events
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	if err := c.UploadDir(ctx, dir, "bucket", "prefix"); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for key := range svc.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) != len(want) {
+		t.Fatalf("want %d objects, got %v", len(want), keys)
+	}
+	for key, content := range want {
+		if got := svc.objects[key]; !bytes.Equal(got, content) {
+			t.Errorf("%s: want %q, got %q", key, content, got)
+		}
+	}
+	if len(svc.uploads) == 0 {
+		t.Error("want some multipart uploads")
+	}
+
+	// check the events.
+	if n := events.count(EventStarted); n != len(want) {
+		t.Errorf("want %d started events, got %d", len(want), n)
+	}
+	if n := events.count(EventCompleted); n != len(want) {
+		t.Errorf("want %d completed events, got %d", len(want), n)
+	}
+	var transferred int64
+	for _, e := range events.events {
+		if e.Type == EventProgress {
+			transferred += e.Bytes
+		}
+	}
+	if transferred != total {
+		t.Errorf("want %d bytes, got %d", total, transferred)
+	}
+}
+
+func TestUpload_DryRun(t *testing.T) {
+	events := &eventRecorder{}
+	c := New(newFakeUploaderAPI(), func(o *Options) {
+		o.DryRun = true
+		o.EventHandler = events
+	})
+	if err := c.Upload(t.Context(), bytes.NewReader([]byte("hello")), "bucket", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 1 {
+		t.Fatalf("want 1 event, got %d", len(events.events))
+	}
+	e := events.events[0]
+	if e.Type != EventSkipped || e.Reason != SkipReasonDryRun || e.Destination != "s3://bucket/key" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestUploadRedirects(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "old.html", "large.html"} {
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte("x"), 20), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := newFakeUploaderAPI()
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.MultipartThreshold = 16
+		o.MultipartChunkSize = 8
+		o.WebsiteRedirects = map[string]string{
+			"site/old.html":   "/new.html",
+			"site/large.html": "https://example.com/large.html",
+		}
+		o.EventHandler = events
+	})
+	if err := c.UploadDir(t.Context(), dir, "bucket", "site"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadRedirects(t.Context(), "bucket", map[string]string{"site/moved/": "/new/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"site/old.html":   "/new.html",
+		"site/large.html": "https://example.com/large.html",
+		"site/moved/":     "/new/",
+	}
+	if len(svc.redirects) != len(want) {
+		t.Errorf("want %v, got %v", want, svc.redirects)
+	}
+	for key, location := range want {
+		if got := svc.redirects[key]; got != location {
+			t.Errorf("%s: want %q, got %q", key, location, got)
+		}
+	}
+	if got, ok := svc.objects["site/moved/"]; !ok || len(got) != 0 {
+		t.Errorf("want an empty object, got %q", got)
+	}
+
+	var redirected bool
+	for _, e := range events.events {
+		if e.Operation == OperationRedirect && e.Type == EventCompleted {
+			redirected = e.Source == "s3://bucket/site/moved/" && e.Destination == "/new/"
+		}
+	}
+	if !redirected {
+		t.Errorf("want a completed redirect event, got %+v", events.events)
+	}
+}
+
+func TestUploadDir_ObjectLock(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "small"), []byte("small"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "large"), bytes.Repeat([]byte("x"), 20), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := newFakeUploaderAPI()
+	c := New(svc, func(o *Options) {
+		o.MultipartThreshold = 8
+		o.MultipartChunkSize = 8
+		o.ObjectLockMode = types.ObjectLockModeCompliance
+		o.ObjectLockRetainUntilDate = &retainUntil
+		o.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	})
+	if err := c.UploadDir(t.Context(), dir, "bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := objectLock{
+		mode:        types.ObjectLockModeCompliance,
+		retainUntil: retainUntil,
+		legalHold:   types.ObjectLockLegalHoldStatusOn,
+		checksum:    types.ChecksumAlgorithmCrc32,
+	}
+	for _, key := range []string{"small", "large"} {
+		if got := svc.locks[key]; got != want {
+			t.Errorf("%s: want %+v, got %+v", key, want, got)
+		}
+	}
+	if got := svc.objects["large"]; len(got) != 20 {
+		t.Errorf("want 20 bytes, got %d", len(got))
+	}
+}
+
+func TestUploadDir_NoOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"small": "small",
+		"large": strings.Repeat("large", 5),
+		"new":   "new",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := newFakeUploaderAPI()
+	svc.objects["small"] = []byte("old")
+	svc.objects["large"] = []byte("old")
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.MultipartThreshold = 16
+		o.MultipartChunkSize = 8
+		o.NoOverwrite = true
+		o.EventHandler = events
+	})
+	if err := c.UploadDir(t.Context(), dir, "bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing objects are kept, and the new object is uploaded.
+	for key, want := range map[string]string{"small": "old", "large": "old", "new": "new"} {
+		if got := string(svc.objects[key]); got != want {
+			t.Errorf("%s: want %q, got %q", key, want, got)
+		}
+	}
+	if len(svc.aborted) != 1 || svc.aborted[0] != "large" {
+		t.Errorf("want the multipart upload of large aborted, got %v", svc.aborted)
+	}
+
+	skipped := map[string]bool{}
+	for _, e := range events.events {
+		if e.Type == EventSkipped && e.Reason == SkipReasonPreconditionFailed {
+			skipped[e.Destination] = true
+		}
+	}
+	if len(skipped) != 2 || !skipped["s3://bucket/small"] || !skipped["s3://bucket/large"] {
+		t.Errorf("want small and large skipped, got %v", skipped)
+	}
+	if n := events.count(EventFailed); n != 0 {
+		t.Errorf("want no failed events, got %d", n)
+	}
+}
+
+func TestUpload_ThresholdSmallerThanChunkSize(t *testing.T) {
+	svc := newFakeUploaderAPI()
+	c := New(svc, func(o *Options) {
+		o.MultipartThreshold = 10
+		o.MultipartChunkSize = 100
+	})
+
+	// the body is larger than the threshold, but fits in a chunk.
+	body := strings.Repeat("a", 50)
+	if err := c.Upload(t.Context(), strings.NewReader(body), "bucket", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(svc.objects["key"]); got != body {
+		t.Errorf("want %q, got %q", body, got)
+	}
+	if len(svc.uploads) != 1 {
+		t.Errorf("want a multipart upload, got %d", len(svc.uploads))
+	}
+
+	// the body within the threshold is uploaded in a single part.
+	if err := c.Upload(t.Context(), strings.NewReader("small"), "bucket", "small"); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.uploads) != 1 {
+		t.Errorf("want no more multipart uploads, got %d", len(svc.uploads))
+	}
+}
+
+func TestUpload_IfMatch(t *testing.T) {
+	svc := newFakeUploaderAPI()
+	svc.objects["manifest.json"] = []byte("v1")
+	events := &eventRecorder{}
+	c := New(svc, func(o *Options) {
+		o.IfMatch = `"v0"`
+		o.EventHandler = events
+	})
+
+	// the ETag doesn't match.
+	if err := c.Upload(t.Context(), bytes.NewReader([]byte("v2")), "bucket", "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(svc.objects["manifest.json"]); got != "v1" {
+		t.Errorf("want v1, got %q", got)
+	}
+	if n := events.count(EventSkipped); n != 1 {
+		t.Errorf("want 1 skipped event, got %d", n)
+	}
+
+	// the ETag matches.
+	c = New(svc, func(o *Options) {
+		o.IfMatch = `"v1"`
+	})
+	if err := c.Upload(t.Context(), bytes.NewReader([]byte("v2")), "bucket", "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(svc.objects["manifest.json"]); got != "v2" {
+		t.Errorf("want v2, got %q", got)
+	}
+}
+
+func TestUploadDir_Preserve(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, size := range map[string]int{"small": 4, "large": 20} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := newFakeUploaderAPI()
+	c := New(svc, func(o *Options) {
+		o.MultipartThreshold = 8
+		o.MultipartChunkSize = 8
+		o.Preserve = true
+	})
+	if err := c.UploadDir(t.Context(), dir, "bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"small", "large"} {
+		if got := svc.metadata[key][metadataMtime]; got != "2020-01-02T03:04:05Z" {
+			t.Errorf("%s: want the modification time, got %q", key, got)
+		}
+		if _, ok := svc.metadata[key][metadataMode]; !ok {
+			t.Errorf("%s: want the mode, got %v", key, svc.metadata[key])
+		}
+	}
+
+	// the metadata is not stored by default.
+	svc = newFakeUploaderAPI()
+	if err := New(svc).UploadDir(t.Context(), dir, "bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.metadata) != 0 {
+		t.Errorf("want no metadata, got %v", svc.metadata)
+	}
+}
+
+func TestUpload_Error(t *testing.T) {
+	events := &eventRecorder{}
+	c := New(&failingUploaderAPI{}, func(o *Options) {
+		o.EventHandler = events
+	})
+	err := c.Upload(t.Context(), bytes.NewReader([]byte("hello")), "bucket", "key")
+	if err == nil || err.Error() != "access denied" {
+		t.Fatalf("want access denied, got %v", err)
+	}
+	if n := events.count(EventFailed); n != 1 {
+		t.Errorf("want 1 failed event, got %d", n)
+	}
+}
+
+type failingUploaderAPI struct {
+	S3API
+}
+
+func (f *failingUploaderAPI) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return nil, fmt.Errorf("access denied")
+}